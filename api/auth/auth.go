@@ -0,0 +1,258 @@
+// Package auth protects the daemon (the API, the stream, and the UI)
+// with a token or a password - in the spirit of Jupyter: a browser
+// logs in once (via the login page or a ?token= link) and gets a
+// session cookie; scripts can send the credentials with every request.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/logging"
+)
+
+const (
+	CookieSession = "kexp-session"
+	QueryToken    = "token"
+
+	PathLogin  = "/login"
+	PathLogout = "/logout"
+
+	sessionTTL = 7 * 24 * time.Hour
+)
+
+//go:embed login.html
+var loginFS embed.FS
+
+var loginPage = template.Must(template.ParseFS(loginFS, "login.html"))
+
+type session struct {
+	principal string
+	expiresAt time.Time
+}
+
+type Auth struct {
+	verifier Verifier
+
+	// The routes' prefix (see --base-path).
+	basePath string
+
+	mux      sync.Mutex
+	sessions map[string]session
+
+	logger *logrus.Entry
+}
+
+func New(verifier Verifier, basePath string, logger *logrus.Entry) *Auth {
+	return &Auth{
+		verifier: verifier,
+		basePath: basePath,
+		sessions: map[string]session{},
+		logger:   logger.WithField("module", "auth"),
+	}
+}
+
+// Middleware lets through the requests with a valid session cookie
+// or credentials (Authorization: Bearer <token> or Basic, or ?token=).
+// The rest get 401 (API) or a redirect to the login page (UI).
+func (a *Auth) Middleware(c *gin.Context) {
+	path := strings.TrimPrefix(c.Request.URL.Path, a.basePath)
+	if path == PathLogin || path == PathLogout {
+		c.Next()
+		return
+	}
+
+	if principal, ok := a.lookupSession(c); ok {
+		a.authenticated(c, principal)
+		return
+	}
+
+	if principal, ok := a.verifyRequest(c); ok {
+		// A ?token= link opened in a browser - log in and drop
+		// the token from the address bar.
+		if c.Query(QueryToken) != "" && c.Request.Method == http.MethodGet && !isAPIPath(path) {
+			a.startSession(c, principal)
+
+			u := *c.Request.URL
+			q := u.Query()
+			q.Del(QueryToken)
+			u.RawQuery = q.Encode()
+			c.Redirect(http.StatusFound, api.ForwardedPrefix(c.Request)+u.RequestURI())
+			c.Abort()
+			return
+		}
+
+		a.authenticated(c, principal)
+		return
+	}
+
+	if isAPIPath(path) {
+		c.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			map[string]string{"error": "unauthorized"},
+		)
+		return
+	}
+
+	next := api.ForwardedPrefix(c.Request) + c.Request.URL.RequestURI()
+	c.Redirect(http.StatusFound, api.BasePath(c)+PathLogin+"?next="+url.QueryEscape(next))
+	c.Abort()
+}
+
+// GET /login
+func (a *Auth) LoginPage(c *gin.Context) {
+	a.renderLogin(c, http.StatusOK, "")
+}
+
+// POST /login (form: [user], password, next, csrfToken)
+func (a *Auth) Login(c *gin.Context) {
+	logger := logging.WithRequestID(c.Request.Context(), a.logger).
+		WithField("user", c.PostForm("user"))
+
+	principal, ok := a.verifier.Verify(c.PostForm("user"), c.PostForm("password"))
+	if !ok {
+		logger.Warn("Login failed")
+		a.renderLogin(c, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	logger.WithField("principal", principal).Info("Logged in")
+
+	a.startSession(c, principal)
+	c.Redirect(http.StatusFound, safeNext(c.PostForm("next"), api.BasePath(c)+"/"))
+}
+
+// POST /logout
+func (a *Auth) Logout(c *gin.Context) {
+	if id, err := c.Cookie(CookieSession); err == nil {
+		a.mux.Lock()
+		delete(a.sessions, id)
+		a.mux.Unlock()
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     CookieSession,
+		Value:    "",
+		Path:     api.BasePath(c) + "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	c.Redirect(http.StatusFound, api.BasePath(c)+PathLogin)
+}
+
+func (a *Auth) authenticated(c *gin.Context, principal string) {
+	c.Request = c.Request.WithContext(
+		context.WithValue(c.Request.Context(), logging.KeyPrincipal, principal),
+	)
+	c.Next()
+}
+
+func (a *Auth) verifyRequest(c *gin.Context) (string, bool) {
+	if user, password, ok := c.Request.BasicAuth(); ok {
+		return a.verifier.Verify(user, password)
+	}
+
+	if token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); found {
+		return a.verifier.Verify("", token)
+	}
+
+	if token := c.Query(QueryToken); token != "" && a.verifier.TokenOnly() {
+		return a.verifier.Verify("", token)
+	}
+
+	return "", false
+}
+
+func (a *Auth) lookupSession(c *gin.Context) (string, bool) {
+	id, err := c.Cookie(CookieSession)
+	if err != nil || id == "" {
+		return "", false
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	s, found := a.sessions[id]
+	if !found {
+		return "", false
+	}
+	if time.Now().After(s.expiresAt) {
+		delete(a.sessions, id)
+		return "", false
+	}
+	return s.principal, true
+}
+
+func (a *Auth) startSession(c *gin.Context, principal string) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		// Something really bad just happened.
+		panic(err.Error())
+	}
+	id := hex.EncodeToString(buf)
+
+	a.mux.Lock()
+	now := time.Now()
+	for sid, s := range a.sessions {
+		if now.After(s.expiresAt) {
+			delete(a.sessions, sid)
+		}
+	}
+	a.sessions[id] = session{principal: principal, expiresAt: now.Add(sessionTTL)}
+	a.mux.Unlock()
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     CookieSession,
+		Value:    id,
+		Path:     api.BasePath(c) + "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		Secure:   api.IsSecure(c.Request),
+		HttpOnly: true,
+		// Lax (not Strict) to keep the session when the UI
+		// is opened via a link (e.g., from a terminal).
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (a *Auth) renderLogin(c *gin.Context, status int, errMsg string) {
+	next := c.PostForm("next")
+	if next == "" {
+		next = c.Query("next")
+	}
+
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := loginPage.Execute(c.Writer, map[string]interface{}{
+		"TokenOnly": a.verifier.TokenOnly(),
+		"Next":      safeNext(next, api.BasePath(c)+"/"),
+		"BasePath":  api.BasePath(c),
+		"CSRFToken": api.CSRFToken(c),
+		"Error":     errMsg,
+	}); err != nil {
+		a.logger.WithError(err).Error("Couldn't render login page")
+	}
+}
+
+func isAPIPath(path string) bool {
+	return strings.HasPrefix(path, "/api/")
+}
+
+// safeNext keeps the post-login redirects on the same site.
+func safeNext(next string, fallback string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return fallback
+	}
+	return next
+}
@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/iximiuz/kexp/logging"
+)
+
+const testToken = "s3cr3t-t0k3n"
+
+func newAuthTestRouter(verifier Verifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	a := New(verifier, "", logrus.NewEntry(logger))
+
+	router := gin.New()
+	router.Use(a.Middleware)
+	router.GET(PathLogin, a.LoginPage)
+	router.POST(PathLogin, a.Login)
+	router.POST(PathLogout, a.Logout)
+
+	whoami := func(c *gin.Context) {
+		c.String(http.StatusOK, logging.Principal(c.Request.Context()))
+	}
+	router.GET("/api/whoami", whoami)
+	router.GET("/ui/*filepath", whoami)
+	return router
+}
+
+func TestMiddlewareToken(t *testing.T) {
+	router := newAuthTestRouter(NewTokenVerifier(testToken))
+
+	tests := []struct {
+		name          string
+		target        string
+		headers       map[string]string
+		wantStatus    int
+		wantPrincipal string
+		wantLocation  string
+		wantSession   bool
+	}{
+		{
+			name:       "API without credentials",
+			target:     "/api/whoami",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "UI without credentials",
+			target:       "/ui/?tab=graph",
+			wantStatus:   http.StatusFound,
+			wantLocation: PathLogin + "?next=" + url.QueryEscape("/ui/?tab=graph"),
+		},
+		{
+			name:          "bearer token",
+			target:        "/api/whoami",
+			headers:       map[string]string{"Authorization": "Bearer " + testToken},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: "token",
+		},
+		{
+			name:       "wrong bearer token",
+			target:     "/api/whoami",
+			headers:    map[string]string{"Authorization": "Bearer nope"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "empty bearer token",
+			target:     "/api/whoami",
+			headers:    map[string]string{"Authorization": "Bearer "},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "token query on API",
+			target:        "/api/whoami?token=" + testToken,
+			wantStatus:    http.StatusOK,
+			wantPrincipal: "token",
+		},
+		{
+			name:         "token link",
+			target:       "/ui/?tab=graph&token=" + testToken,
+			wantStatus:   http.StatusFound,
+			wantLocation: "/ui/?tab=graph",
+			wantSession:  true,
+		},
+		{
+			name:         "wrong token link",
+			target:       "/ui/?token=nope",
+			wantStatus:   http.StatusFound,
+			wantLocation: PathLogin + "?next=" + url.QueryEscape("/ui/?token=nope"),
+		},
+		{
+			name:       "login page",
+			target:     PathLogin,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantPrincipal != "" && rec.Body.String() != tt.wantPrincipal {
+				t.Errorf("principal = %q, want %q", rec.Body.String(), tt.wantPrincipal)
+			}
+			if loc := rec.Header().Get("Location"); loc != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", loc, tt.wantLocation)
+			}
+			if got := sessionCookie(rec) != nil; got != tt.wantSession {
+				t.Errorf("session started = %v, want %v", got, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	router := newAuthTestRouter(NewTokenVerifier(testToken))
+
+	// Log in via the form...
+	form := url.Values{"password": {testToken}, "next": {"/ui/objects"}}
+	req := httptest.NewRequest(http.MethodPost, PathLogin, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/ui/objects" {
+		t.Fatalf("login: status = %d, Location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookie := sessionCookie(rec)
+	if cookie == nil {
+		t.Fatal("login: no session cookie")
+	}
+	if !cookie.HttpOnly {
+		t.Error("session cookie must be HttpOnly")
+	}
+
+	// ...use the session...
+	req = httptest.NewRequest(http.MethodGet, "/api/whoami", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "token" {
+		t.Fatalf("session: status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+
+	// ...and log out.
+	req = httptest.NewRequest(http.MethodPost, PathLogout, nil)
+	req.AddCookie(cookie)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/whoami", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("after logout: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLoginFailed(t *testing.T) {
+	router := newAuthTestRouter(NewTokenVerifier(testToken))
+
+	form := url.Values{"password": {"nope"}, "next": {"/ui/"}}
+	req := httptest.NewRequest(http.MethodPost, PathLogin, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if sessionCookie(rec) != nil {
+		t.Error("failed login started a session")
+	}
+}
+
+func TestMiddlewareHtpasswd(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("alice-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte("# users\nalice:"+string(hash)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := LoadHtpasswd(path)
+	if err != nil {
+		t.Fatalf("LoadHtpasswd() failed: %v", err)
+	}
+	router := newAuthTestRouter(verifier)
+
+	tests := []struct {
+		name          string
+		target        string
+		user          string
+		password      string
+		wantStatus    int
+		wantPrincipal string
+	}{
+		{"valid user", "/api/whoami", "alice", "alice-password", http.StatusOK, "alice"},
+		{"wrong password", "/api/whoami", "alice", "nope", http.StatusUnauthorized, ""},
+		{"unknown user", "/api/whoami", "bob", "alice-password", http.StatusUnauthorized, ""},
+		// No user name to go with a ?token= - the password can't be used as one.
+		{"token query", "/api/whoami?token=alice-password", "", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantPrincipal != "" && rec.Body.String() != tt.wantPrincipal {
+				t.Errorf("principal = %q, want %q", rec.Body.String(), tt.wantPrincipal)
+			}
+		})
+	}
+}
+
+func TestLoadHtpasswdInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", "# nobody\n"},
+		{"no hash", "alice\n"},
+		{"md5 hash", "alice:$apr1$x1y2z3$abcdefghijklmnopqrstu.\n"},
+		{"sha1 hash", "alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "htpasswd")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := LoadHtpasswd(path); err == nil {
+				t.Error("LoadHtpasswd() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestSafeNext(t *testing.T) {
+	tests := []struct {
+		next string
+		want string
+	}{
+		{"/ui/objects?tab=yaml", "/ui/objects?tab=yaml"},
+		{"", "/fallback"},
+		{"https://evil.example/", "/fallback"},
+		{"//evil.example/", "/fallback"},
+		{"/\\evil.example/", "/fallback"},
+		{"javascript:alert(1)", "/fallback"},
+	}
+
+	for _, tt := range tests {
+		if got := safeNext(tt.next, "/fallback"); got != tt.want {
+			t.Errorf("safeNext(%q) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
+
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == CookieSession && cookie.Value != "" {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Verifier checks the credentials and returns the authenticated principal.
+type Verifier interface {
+	Verify(user, password string) (principal string, ok bool)
+
+	// Token verifiers need no user name (the login form asks only for a token).
+	TokenOnly() bool
+}
+
+type tokenVerifier struct {
+	token string
+}
+
+// NewTokenVerifier accepts the token (with any user name).
+func NewTokenVerifier(token string) Verifier {
+	return &tokenVerifier{token: token}
+}
+
+func (v *tokenVerifier) Verify(_, password string) (string, bool) {
+	if subtle.ConstantTimeCompare([]byte(password), []byte(v.token)) != 1 {
+		return "", false
+	}
+	return "token", true
+}
+
+func (v *tokenVerifier) TokenOnly() bool {
+	return true
+}
+
+// GenerateToken returns a random token (for when no credentials are configured).
+func GenerateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("couldn't generate access token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ReadTokenFile reads a token from the file (e.g., a mounted Secret).
+func ReadTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read token file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+type htpasswdVerifier struct {
+	users map[string][]byte
+}
+
+// LoadHtpasswd reads user:hash lines. Only bcrypt hashes are
+// supported (htpasswd -B) - the other htpasswd formats are weak.
+func LoadHtpasswd(path string) (Verifier, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read htpasswd file: %w", err)
+	}
+	defer f.Close()
+
+	v := &htpasswdVerifier{users: map[string][]byte{}}
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		user, hash, found := strings.Cut(text, ":")
+		if !found || user == "" {
+			return nil, fmt.Errorf("htpasswd line %d: expected user:hash", line)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("htpasswd line %d: only bcrypt hashes are supported (htpasswd -B)", line)
+		}
+		v.users[user] = []byte(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(v.users) == 0 {
+		return nil, errors.New("htpasswd file has no users")
+	}
+	return v, nil
+}
+
+func (v *htpasswdVerifier) Verify(user, password string) (string, bool) {
+	hash, found := v.users[user]
+	if !found {
+		return "", false
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return "", false
+	}
+	return user, true
+}
+
+func (v *htpasswdVerifier) TokenOnly() bool {
+	return false
+}
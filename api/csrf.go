@@ -0,0 +1,218 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Same names as axios (the UI's HTTP client) uses by default.
+	CookieCSRFToken = "XSRF-TOKEN"
+	HeaderCSRFToken = "X-XSRF-TOKEN"
+
+	// Browsers can't set custom headers on WebSocket upgrade requests
+	// (and on HTML form submissions), hence the query string (and
+	// the form field) alternative.
+	QueryCSRFToken = "csrfToken"
+	FormCSRFToken  = "csrfToken"
+
+	contextKeyCSRFToken = "kexp.csrfToken"
+)
+
+// CSRFProtection keeps other websites open in the user's browser from
+// driving the API (and the user's cluster credentials):
+//
+//   - Requests for unknown hosts are rejected - otherwise, a page that
+//     rebinds its DNS name to kexp's address (DNS rebinding) would be
+//     treated as same-origin.
+//   - Requests from foreign origins are rejected (unless allowed explicitly).
+//   - Browser requests that change something (and the stream upgrades)
+//     must carry a token that only the UI's origin can read - the token
+//     is generated on start and sent to the UI in a cookie.
+//
+// Non-browser clients (curl, scripts) can't be used for CSRF, so they
+// don't need the token.
+type CSRFProtection struct {
+	token string
+
+	anyOrigin bool
+	origins   map[string]bool
+
+	anyHost bool
+	hosts   map[string]bool
+}
+
+// NewCSRFProtection accepts the allowed origins in the scheme://host[:port]
+// form and the allowed hosts (names or IPs, no ports) the server can be
+// reached by. "*" allows any origin or any host (don't!).
+func NewCSRFProtection(allowedOrigins []string, allowedHosts []string) (*CSRFProtection, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("couldn't generate CSRF token: %w", err)
+	}
+
+	p := &CSRFProtection{
+		token:   hex.EncodeToString(buf),
+		origins: map[string]bool{},
+		hosts:   map[string]bool{},
+	}
+
+	for _, host := range allowedHosts {
+		if host == "*" {
+			p.anyHost = true
+			continue
+		}
+
+		if host == "" || (strings.ContainsAny(host, "/:@") && net.ParseIP(host) == nil) {
+			return nil, fmt.Errorf("invalid host %q - expected a name or an IP address", host)
+		}
+		p.hosts[normalizeHost(host)] = true
+	}
+
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			p.anyOrigin = true
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("invalid origin %q - expected scheme://host[:port]", origin)
+		}
+		p.origins[strings.ToLower(u.Scheme+"://"+u.Host)] = true
+	}
+
+	return p, nil
+}
+
+// CheckHost allows requests with the Host header (port aside) naming
+// one of the allowed hosts.
+func (p *CSRFProtection) CheckHost(r *http.Request) bool {
+	if p.anyHost {
+		return true
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return p.hosts[normalizeHost(host)]
+}
+
+// CheckOrigin allows requests with no Origin header (non-browser clients),
+// same-origin requests (incl. the ones coming through a reverse proxy that
+// rewrites the Host header), and requests from the allowed origins.
+// It can be used as websocket.Upgrader.CheckOrigin.
+func (p *CSRFProtection) CheckOrigin(r *http.Request) bool {
+	if !p.CheckHost(r) {
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || p.anyOrigin {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if host := r.Header.Get(HeaderForwardedHost); host != "" && strings.EqualFold(u.Host, host) {
+		return true
+	}
+	return p.origins[strings.ToLower(u.Scheme+"://"+u.Host)]
+}
+
+func (p *CSRFProtection) Middleware(c *gin.Context) {
+	// Before anything else - the cookie must never reach a rebinding page.
+	if !p.CheckHost(c.Request) {
+		c.AbortWithStatusJSON(
+			http.StatusForbidden,
+			map[string]string{"error": "host not allowed (see --allowed-host)"},
+		)
+		return
+	}
+
+	if !p.CheckOrigin(c.Request) {
+		c.AbortWithStatusJSON(
+			http.StatusForbidden,
+			map[string]string{"error": "origin not allowed"},
+		)
+		return
+	}
+
+	if cookie, err := c.Cookie(CookieCSRFToken); err != nil || cookie != p.token {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     CookieCSRFToken,
+			Value:    p.token,
+			Path:     BasePath(c) + "/",
+			Secure:   IsSecure(c.Request),
+			HttpOnly: false, // The UI needs to read it.
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+
+	c.Set(contextKeyCSRFToken, p.token)
+
+	if isBrowserRequest(c.Request) && (isMutatingRequest(c.Request) || isWebSocketUpgrade(c.Request)) {
+		token := c.GetHeader(HeaderCSRFToken)
+		if token == "" && isWebSocketUpgrade(c.Request) {
+			token = c.Query(QueryCSRFToken)
+		}
+		if token == "" && c.ContentType() == "application/x-www-form-urlencoded" {
+			token = c.PostForm(FormCSRFToken)
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(p.token)) != 1 {
+			c.AbortWithStatusJSON(
+				http.StatusForbidden,
+				map[string]string{"error": "invalid CSRF token"},
+			)
+			return
+		}
+	}
+
+	c.Next()
+}
+
+// "LocalHost." -> "localhost", "[::1]" -> "::1"
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// Browsers send Sec-Fetch-Site with every request (and Origin with
+// all but same-origin GETs), and page scripts can't remove them.
+func isBrowserRequest(r *http.Request) bool {
+	return r.Header.Get("Origin") != "" || r.Header.Get("Sec-Fetch-Site") != ""
+}
+
+func isMutatingRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// CSRFToken returns the token for the pages rendered server-side
+// (e.g., to put into a form).
+func CSRFToken(c *gin.Context) string {
+	return c.GetString(contextKeyCSRFToken)
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCSRFTestRouter(t *testing.T, origins, hosts []string) (*gin.Engine, *CSRFProtection) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	csrf, err := NewCSRFProtection(origins, hosts)
+	if err != nil {
+		t.Fatalf("NewCSRFProtection() failed: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(csrf.Middleware)
+	router.GET("/api/objects", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.DELETE("/api/objects", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router, csrf
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	router, csrf := newCSRFTestRouter(
+		t,
+		[]string{"https://dashboard.example.com"},
+		[]string{"localhost", "127.0.0.1", "::1", "kexp.example.com"},
+	)
+
+	tests := []struct {
+		name       string
+		method     string
+		host       string
+		headers    map[string]string
+		wantStatus int
+		wantCookie bool
+	}{
+		{
+			name:       "non-browser client",
+			method:     http.MethodDelete,
+			host:       "localhost:5173",
+			wantStatus: http.StatusOK,
+			wantCookie: true,
+		},
+		{
+			name:       "same-origin read",
+			method:     http.MethodGet,
+			host:       "localhost:5173",
+			headers:    map[string]string{"Sec-Fetch-Site": "same-origin"},
+			wantStatus: http.StatusOK,
+			wantCookie: true,
+		},
+		{
+			name:   "same-origin mutation with token",
+			method: http.MethodDelete,
+			host:   "127.0.0.1:5173",
+			headers: map[string]string{
+				"Origin":        "http://127.0.0.1:5173",
+				HeaderCSRFToken: csrf.token,
+			},
+			wantStatus: http.StatusOK,
+			wantCookie: true,
+		},
+		{
+			name:       "same-origin mutation without token",
+			method:     http.MethodDelete,
+			host:       "localhost:5173",
+			headers:    map[string]string{"Origin": "http://localhost:5173"},
+			wantStatus: http.StatusForbidden,
+			wantCookie: true,
+		},
+		{
+			name:   "same-origin mutation with wrong token",
+			method: http.MethodDelete,
+			host:   "localhost:5173",
+			headers: map[string]string{
+				"Origin":        "http://localhost:5173",
+				HeaderCSRFToken: "forged",
+			},
+			wantStatus: http.StatusForbidden,
+			wantCookie: true,
+		},
+		{
+			name:   "allowed origin",
+			method: http.MethodDelete,
+			host:   "kexp.example.com",
+			headers: map[string]string{
+				"Origin":        "https://dashboard.example.com",
+				HeaderCSRFToken: csrf.token,
+			},
+			wantStatus: http.StatusOK,
+			wantCookie: true,
+		},
+		{
+			name:       "foreign origin",
+			method:     http.MethodGet,
+			host:       "localhost:5173",
+			headers:    map[string]string{"Origin": "https://evil.example"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "foreign origin pretending to be proxied",
+			method: http.MethodGet,
+			host:   "localhost:5173",
+			headers: map[string]string{
+				"Origin":            "https://evil.example",
+				HeaderForwardedHost: "localhost:5173",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "DNS rebinding",
+			method: http.MethodGet,
+			host:   "evil.example:5173",
+			headers: map[string]string{
+				"Origin":         "http://evil.example:5173",
+				"Sec-Fetch-Site": "same-origin",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "DNS rebinding with forwarded host",
+			method: http.MethodDelete,
+			host:   "evil.example:5173",
+			headers: map[string]string{
+				"Origin":            "http://evil.example:5173",
+				HeaderForwardedHost: "localhost:5173",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "unknown host without origin",
+			method:     http.MethodGet,
+			host:       "evil.example",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "IPv6 loopback",
+			method:     http.MethodGet,
+			host:       "[::1]:5173",
+			wantStatus: http.StatusOK,
+			wantCookie: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/objects", nil)
+			req.Host = tt.host
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			gotCookie := false
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.Name == CookieCSRFToken {
+					gotCookie = true
+				}
+			}
+			if gotCookie != tt.wantCookie {
+				t.Errorf("CSRF cookie issued = %v, want %v", gotCookie, tt.wantCookie)
+			}
+		})
+	}
+}
+
+func TestCSRFCheckOriginWebSocket(t *testing.T) {
+	csrf, err := NewCSRFProtection(nil, []string{"localhost"})
+	if err != nil {
+		t.Fatalf("NewCSRFProtection() failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		want   bool
+	}{
+		{"same origin", "localhost:5173", "http://localhost:5173", true},
+		{"no origin", "localhost:5173", "", true},
+		{"foreign origin", "localhost:5173", "http://evil.example", false},
+		{"DNS rebinding", "evil.example:5173", "http://evil.example:5173", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/stream/v1/", nil)
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			if got := csrf.CheckOrigin(req); got != tt.want {
+				t.Errorf("CheckOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCSRFProtectionInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		hosts   []string
+	}{
+		{"origin without scheme", []string{"example.com"}, nil},
+		{"origin with path", []string{"https://example.com/kexp"}, nil},
+		{"host with port", nil, []string{"example.com:5173"}},
+		{"host as URL", nil, []string{"https://example.com"}},
+		{"empty host", nil, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCSRFProtection(tt.origins, tt.hosts); err == nil {
+				t.Error("NewCSRFProtection() succeeded, want error")
+			}
+		})
+	}
+}
@@ -36,5 +36,8 @@ func NewHandler(name string, logger *logrus.Entry) Handler {
 }
 
 func (h *Handler) Logger(c *gin.Context) *logrus.Entry {
-	return logging.WithRequestID(c.Request.Context(), h.logger)
+	return logging.WithPrincipal(
+		c.Request.Context(),
+		logging.WithRequestID(c.Request.Context(), h.logger),
+	)
 }
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+const (
+	HeaderImpersonateUser  = "Impersonate-User"
+	HeaderImpersonateGroup = "Impersonate-Group"
+
+	// Browsers can't set custom headers on WebSocket upgrade
+	// requests, hence the query string alternative.
+	QueryImpersonateUser  = "impersonateUser"
+	QueryImpersonateGroup = "impersonateGroup"
+)
+
+// MiddlewareImpersonation attaches the requested identity (if any)
+// to the request context. Handlers pick it up via ClientPool.ContextFor.
+func MiddlewareImpersonation(c *gin.Context) {
+	imp := kubeclient.Impersonation{
+		User:   c.GetHeader(HeaderImpersonateUser),
+		Groups: c.Request.Header.Values(HeaderImpersonateGroup),
+	}
+	if imp.User == "" {
+		imp.User = c.Query(QueryImpersonateUser)
+	}
+	if len(imp.Groups) == 0 {
+		imp.Groups = c.QueryArray(QueryImpersonateGroup)
+	}
+
+	if imp.User == "" && len(imp.Groups) > 0 {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			map[string]string{"error": "impersonating groups requires a user"},
+		)
+		return
+	}
+
+	if !imp.IsZero() {
+		c.Request = c.Request.WithContext(
+			kubeclient.WithImpersonation(c.Request.Context(), imp),
+		)
+	}
+	c.Next()
+}
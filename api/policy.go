@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iximiuz/kexp/policy"
+)
+
+// Route identifies a route by its method and full path pattern
+// (as registered, e.g., /api/kube/v1/contexts/:ctx/snapshots/).
+type Route struct {
+	Method string
+	Path   string
+}
+
+// MiddlewareReadOnly rejects all requests but reads (GET and HEAD)
+// and the exempt routes when the policy is read-only. It's meant for
+// route groups - unlike MiddlewarePolicy, it can't be forgotten
+// on a newly added route.
+func MiddlewareReadOnly(pol *policy.Policy, exempt ...Route) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if pol == nil || !pol.ReadOnly {
+			c.Next()
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+			c.Next()
+			return
+		}
+
+		for _, route := range exempt {
+			if route.Method == c.Request.Method && route.Path == c.FullPath() {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(
+			http.StatusForbidden,
+			map[string]string{"error": "kexp is in read-only mode"},
+		)
+	}
+}
+
+// MiddlewarePolicy guards a route performing the action (verb) on the
+// objects identified by the route's :ctx, :group, :resource, :namespace,
+// and :name params. Every route that changes something must have one.
+func MiddlewarePolicy(pol *policy.Policy, verb string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := c.Param("group")
+		if group == "core" {
+			group = ""
+		}
+
+		action := policy.Action{
+			Verb:      verb,
+			Context:   c.Param("ctx"),
+			Group:     group,
+			Resource:  c.Param("resource"),
+			Namespace: c.Param("namespace"),
+			Name:      c.Param("name"),
+		}
+		if !pol.Allows(action) {
+			c.AbortWithStatusJSON(
+				http.StatusForbidden,
+				map[string]string{"error": verb + " is not allowed by kexp policy"},
+			)
+			return
+		}
+
+		c.Next()
+	}
+}
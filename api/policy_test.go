@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iximiuz/kexp/policy"
+)
+
+func newPolicyTestRouter(pol *policy.Policy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	router := gin.New()
+	kubeContext := router.Group("/api/kube/v1/contexts/:ctx")
+	kubeContext.Use(MiddlewareReadOnly(
+		pol,
+		Route{Method: http.MethodPost, Path: kubeContext.BasePath() + "/snapshots/"},
+	))
+
+	objects := kubeContext.Group("/resources")
+	objects.GET("/:group/:version/namespaces/:namespace/:resource/:name/", ok)
+	objects.PUT("/:group/:version/namespaces/:namespace/:resource/:name/", MiddlewarePolicy(pol, policy.VerbUpdate), ok)
+	objects.DELETE("/:group/:version/namespaces/:namespace/:resource/:name/", MiddlewarePolicy(pol, policy.VerbDelete), ok)
+	objects.DELETE("/:group/:version/:resource/:name/", MiddlewarePolicy(pol, policy.VerbDelete), ok)
+
+	// A mutating route somebody forgot to guard with MiddlewarePolicy.
+	kubeContext.Group("/exec").POST("/:namespace/:name/", ok)
+
+	kubeContext.Group("/snapshots").POST("/", ok)
+	return router
+}
+
+func TestMiddlewareReadOnlyAndPolicy(t *testing.T) {
+	readOnly := &policy.Policy{ReadOnly: true, Rules: []policy.Rule{}}
+	noKubeSystemDeletes := &policy.Policy{Rules: []policy.Rule{{
+		Effect:     policy.EffectDeny,
+		Verbs:      []string{policy.VerbDelete},
+		Namespaces: []string{"kube-system"},
+	}}}
+
+	tests := []struct {
+		name       string
+		pol        *policy.Policy
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{"read-only: get", readOnly, http.MethodGet, "/api/kube/v1/contexts/prod/resources/apps/v1/namespaces/default/deployments/web/", http.StatusOK},
+		{"read-only: update", readOnly, http.MethodPut, "/api/kube/v1/contexts/prod/resources/apps/v1/namespaces/default/deployments/web/", http.StatusForbidden},
+		{"read-only: delete", readOnly, http.MethodDelete, "/api/kube/v1/contexts/prod/resources/apps/v1/namespaces/default/deployments/web/", http.StatusForbidden},
+		{"read-only: unguarded route", readOnly, http.MethodPost, "/api/kube/v1/contexts/prod/exec/default/web/", http.StatusForbidden},
+		{"read-only: exempt route", readOnly, http.MethodPost, "/api/kube/v1/contexts/prod/snapshots/", http.StatusOK},
+		{"no policy: delete", nil, http.MethodDelete, "/api/kube/v1/contexts/prod/resources/apps/v1/namespaces/default/deployments/web/", http.StatusOK},
+		{"rules: delete elsewhere", noKubeSystemDeletes, http.MethodDelete, "/api/kube/v1/contexts/prod/resources/apps/v1/namespaces/default/deployments/web/", http.StatusOK},
+		{"rules: delete in namespace", noKubeSystemDeletes, http.MethodDelete, "/api/kube/v1/contexts/prod/resources/apps/v1/namespaces/kube-system/deployments/coredns/", http.StatusForbidden},
+		{"rules: update in namespace", noKubeSystemDeletes, http.MethodPut, "/api/kube/v1/contexts/prod/resources/apps/v1/namespaces/kube-system/deployments/coredns/", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newPolicyTestRouter(tt.pol).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
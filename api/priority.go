@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+// MiddlewarePriority marks the requests the user is likely waiting for
+// (getting a single object, changing something) as interactive, so
+// their Kubernetes API calls get ahead of the background lists and watches.
+func MiddlewarePriority(c *gin.Context) {
+	if c.Request.Method != http.MethodGet || c.Param("name") != "" {
+		c.Request = c.Request.WithContext(
+			kubeclient.WithPriority(c.Request.Context(), kubeclient.PriorityInteractive),
+		)
+	}
+	c.Next()
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Set by reverse proxies that strip a path prefix (e.g., an ingress
+	// serving kexp at /tools/kexp/) or terminate TLS.
+	HeaderForwardedPrefix = "X-Forwarded-Prefix"
+	HeaderForwardedProto  = "X-Forwarded-Proto"
+	HeaderForwardedHost   = "X-Forwarded-Host"
+
+	contextKeyBasePath = "kexp.basePath"
+)
+
+// MiddlewareBasePath remembers the path prefix kexp is reachable at
+// from the browser's perspective - the (optional) prefix stripped by
+// a reverse proxy followed by the --base-path routes are served under.
+func MiddlewareBasePath(basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextKeyBasePath, ForwardedPrefix(c.Request)+basePath)
+		c.Next()
+	}
+}
+
+// BasePath returns the external base path (no trailing slash) - prepend
+// it to the paths of the links, redirects, and cookies sent to the browser.
+func BasePath(c *gin.Context) string {
+	return c.GetString(contextKeyBasePath)
+}
+
+// NormalizeBasePath turns "", "/", "tools/kexp/", etc.
+// into "" or "/tools/kexp" forms.
+func NormalizeBasePath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+func ForwardedPrefix(r *http.Request) string {
+	prefix := NormalizeBasePath(r.Header.Get(HeaderForwardedPrefix))
+	if strings.ContainsAny(prefix, "\"'<>\\") {
+		return "" // Not a path.
+	}
+	return prefix
+}
+
+// IsSecure tells if the browser talks to kexp (or the proxy in front of it) over HTTPS.
+func IsSecure(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get(HeaderForwardedProto), "https")
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/audit"
+)
+
+const defaultLimit = 100
+
+type Handler struct {
+	api.Handler
+
+	auditLog *audit.Log
+}
+
+func NewHandler(auditLog *audit.Log, logger *logrus.Entry) *Handler {
+	return &Handler{
+		Handler:  api.NewHandler("audit", logger),
+		auditLog: auditLog,
+	}
+}
+
+// Lists the audit log entries, the most recent first.
+// The since param is either a timestamp (RFC 3339) or a duration (e.g., 1h).
+//
+// GET audit?[principal=P][&verb=V][&context=C][&resource=R][&namespace=NS][&name=N][&since=T][&limit=L]
+func (h *Handler) List(c *gin.Context) {
+	q := audit.Query{
+		Principal: c.Query("principal"),
+		Verb:      c.Query("verb"),
+		Context:   c.Query("context"),
+		Resource:  c.Query("resource"),
+		Namespace: c.Query("namespace"),
+		Name:      c.Query("name"),
+		Limit:     defaultLimit,
+	}
+
+	if since := c.Query("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			q.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			q.Since = t
+		} else {
+			c.AbortWithStatusJSON(
+				http.StatusBadRequest,
+				map[string]string{"error": "since must be a duration or an RFC 3339 timestamp"},
+			)
+			return
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			c.AbortWithStatusJSON(
+				http.StatusBadRequest,
+				map[string]string{"error": "limit must be a non-negative integer"},
+			)
+			return
+		}
+		q.Limit = n
+	}
+
+	c.JSON(http.StatusOK, h.auditLog.Find(q))
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/policy"
+)
+
+type Handler struct {
+	api.Handler
+
+	version string
+	policy  *policy.Policy
+}
+
+func NewHandler(version string, pol *policy.Policy, logger *logrus.Entry) *Handler {
+	return &Handler{
+		Handler: api.NewHandler("config", logger),
+		version: version,
+		policy:  pol,
+	}
+}
+
+type Response struct {
+	Version string `json:"version"`
+
+	// The UI hides the actions the policy doesn't allow.
+	Policy *policy.Policy `json:"policy"`
+}
+
+// GET config
+func (h *Handler) Get(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Version: h.version,
+		Policy:  h.policy,
+	})
+}
@@ -34,6 +34,8 @@ func (h *Handler) List(c *gin.Context) {
 			ClusterUID: kctx.ClusterUID(),
 			Namespace:  kctx.Namespace(),
 			Current:    kctx.Name() == h.clientPool.CurrentContext().Name(),
+			Offline:    kctx.Static(),
+			Throttling: kctx.Throttling(),
 		})
 	}
 
@@ -47,4 +49,8 @@ type Context struct {
 	ClusterUID string `json:"clusterUID"`
 	Namespace  string `json:"namespace"`
 	Current    bool   `json:"current"`
+	Offline    bool   `json:"offline"`
+
+	// Client-side rate limiting stats (none for offline contexts).
+	Throttling *kubeclient.Throttling `json:"throttling,omitempty"`
 }
@@ -0,0 +1,202 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/diff"
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+type Handler struct {
+	api.Handler
+
+	clientPool *kubeclient.ClientPool
+}
+
+func NewHandler(clientPool *kubeclient.ClientPool, logger *logrus.Entry) *Handler {
+	return &Handler{
+		Handler:    api.NewHandler("kube/diff", logger),
+		clientPool: clientPool,
+	}
+}
+
+type Side struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type Response struct {
+	Left    Side              `json:"left"`
+	Right   Side              `json:"right"`
+	Objects []diff.ObjectDiff `json:"objects"`
+}
+
+// Compares the object (or the objects matching the selectors) in the
+// context and namespace from the path (left) with the same object(s)
+// in the other context and/or namespace (right). The objects are
+// normalized before the comparison (see diff.Normalize).
+//
+// GET kube/v1/contexts/<ctx>/diff/<group>/<version>/<resource>[/<name>]?against=<ctx>[&labelSelector=S][&fieldSelector=S]
+// GET kube/v1/contexts/<ctx>/diff/<group>/<version>/namespaces/<ns>/<resource>[/<name>]?against=<ctx>&againstNamespace=<ns>[&labelSelector=S][&fieldSelector=S]
+func (h *Handler) Get(c *gin.Context) {
+	logger := h.Logger(c).
+		WithField("method", "Get").
+		WithField("context", c.Param("ctx")).
+		WithField("group", c.Param("group")).
+		WithField("version", c.Param("version")).
+		WithField("resource", c.Param("resource")).
+		WithField("namespace", c.Param("namespace")).
+		WithField("name", c.Param("name")).
+		WithField("against", c.Query("against")).
+		WithField("againstNamespace", c.Query("againstNamespace"))
+
+	group := c.Param("group")
+	if group == "core" {
+		group = ""
+	}
+
+	gvr := schema.GroupVersionResource{
+		Group:    group,
+		Version:  c.Param("version"),
+		Resource: c.Param("resource"),
+	}
+
+	left := Side{Context: c.Param("ctx"), Namespace: c.Param("namespace")}
+	right := Side{Context: c.Query("against"), Namespace: c.Query("againstNamespace")}
+	if right.Context == "" {
+		right.Context = left.Context
+	}
+	if right.Namespace == "" {
+		right.Namespace = left.Namespace
+	}
+	if left == right {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			map[string]string{"error": "nothing to compare - specify another context or namespace"},
+		)
+		return
+	}
+
+	var sides [2][]*unstructured.Unstructured
+	for i, side := range []Side{left, right} {
+		kctx, err := h.clientPool.ContextFor(c.Request.Context(), side.Context)
+		if err != nil {
+			logger.
+				WithError(err).
+				WithField("side", side.Context).
+				Error("Unknown context")
+			c.AbortWithStatusJSON(
+				http.StatusNotFound,
+				map[string]string{"error": "unknown context " + side.Context},
+			)
+			return
+		}
+
+		objs, err := fetch(c.Request.Context(), kctx, gvr, side.Namespace, c.Param("name"), metav1.ListOptions{
+			FieldSelector: c.Query("fieldSelector"),
+			LabelSelector: c.Query("labelSelector"),
+		})
+		if err != nil {
+			if apierrors.IsForbidden(err) {
+				c.AbortWithStatusJSON(
+					http.StatusForbidden,
+					map[string]string{"error": "forbidden"},
+				)
+				return
+			}
+
+			if apierrors.IsNotFound(err) {
+				c.AbortWithStatusJSON(
+					http.StatusNotFound,
+					map[string]string{"error": "unknown resource in context " + side.Context},
+				)
+				return
+			}
+
+			logger.
+				WithError(err).
+				WithField("side", side.Context).
+				Error("Couldn't fetch Kubernetes objects")
+			c.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				map[string]string{"error": "internal server error"},
+			)
+			return
+		}
+		sides[i] = objs
+	}
+
+	// Two namespaces are compared object by object, but the same
+	// namespace (or all of them) - only within each namespace.
+	c.JSON(http.StatusOK, Response{
+		Left:    left,
+		Right:   right,
+		Objects: diff.Objects(sides[0], sides[1], left.Namespace == right.Namespace),
+	})
+}
+
+// fetch gets the named object (none if it doesn't exist)
+// or lists the objects matching the options.
+func fetch(
+	ctx context.Context,
+	kctx *kubeclient.Context,
+	gvr schema.GroupVersionResource,
+	namespace string,
+	name string,
+	opts metav1.ListOptions,
+) ([]*unstructured.Unstructured, error) {
+	client, err := kctx.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		obj, err := client.
+			Resource(gvr).
+			Namespace(namespace).
+			Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if isObjectNotFound(err, name) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []*unstructured.Unstructured{obj}, nil
+	}
+
+	list, err := client.
+		Resource(gvr).
+		Namespace(namespace).
+		List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := []*unstructured.Unstructured{}
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return objs, nil
+}
+
+// isObjectNotFound tells a missing object from a missing resource
+// (both are 404s, but only the former has the object's name).
+func isObjectNotFound(err error, name string) bool {
+	var status apierrors.APIStatus
+	if !apierrors.IsNotFound(err) || !errors.As(err, &status) {
+		return false
+	}
+
+	details := status.Status().Details
+	return details != nil && details.Name == name
+}
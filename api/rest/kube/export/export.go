@@ -0,0 +1,305 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/presets"
+	"github.com/iximiuz/kexp/relations"
+)
+
+type Handler struct {
+	api.Handler
+
+	clientPool *kubeclient.ClientPool
+	registry   *presets.Registry
+}
+
+func NewHandler(
+	clientPool *kubeclient.ClientPool,
+	registry *presets.Registry,
+	logger *logrus.Entry,
+) *Handler {
+	return &Handler{
+		Handler:    api.NewHandler("kube/export", logger),
+		clientPool: clientPool,
+		registry:   registry,
+	}
+}
+
+// Exports the graph of objects related to the target (same as the relations API).
+//
+// GET kube/v1/contexts/<ctx>/export/relations/<group>/<version>/<resource>/<name>[?format=dot|mermaid|graphml|tree][&depth=N][&preset=P]
+// GET kube/v1/contexts/<ctx>/export/relations/<group>/<version>/namespaces/<ns>/<resource>/<name>[?format=...][&depth=N][&preset=P]
+func (h *Handler) Relations(c *gin.Context) {
+	logger := h.Logger(c).
+		WithField("method", "Relations").
+		WithField("context", c.Param("ctx")).
+		WithField("group", c.Param("group")).
+		WithField("version", c.Param("version")).
+		WithField("resource", c.Param("resource")).
+		WithField("namespace", c.Param("namespace")).
+		WithField("name", c.Param("name")).
+		WithField("preset", c.Query("preset")).
+		WithField("format", c.Query("format"))
+
+	format, ok := parseFormat(c)
+	if !ok {
+		return
+	}
+
+	depth := relations.DefaultDepth
+	if c.Query("depth") != "" {
+		d, err := strconv.Atoi(c.Query("depth"))
+		if err != nil || d < 0 || d > relations.MaxDepth {
+			c.AbortWithStatusJSON(
+				http.StatusBadRequest,
+				map[string]string{"error": "invalid depth"},
+			)
+			return
+		}
+		depth = d
+	}
+
+	_, src, preset, ok := h.prepare(c, logger)
+	if !ok {
+		return
+	}
+
+	gvk, ok := mapResource(c, logger, src)
+	if !ok {
+		return
+	}
+
+	graph, err := preset.Engine(src).Related(c.Request.Context(), relations.ObjectRef{
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Namespace: c.Param("namespace"),
+		Name:      c.Param("name"),
+	}, depth)
+	if err != nil {
+		if errors.Is(err, relations.ErrNotFound) {
+			c.AbortWithStatusJSON(
+				http.StatusNotFound,
+				map[string]string{"error": "not found"},
+			)
+			return
+		}
+
+		logger.
+			WithError(err).
+			Error("Couldn't compute related objects")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	h.write(c, logger, graph, format)
+}
+
+// Exports the objects of a resource (same as a resource watch) and the
+// relations between them.
+//
+// GET kube/v1/contexts/<ctx>/export/resources/<group>/<version>/<resource>[?format=...][&labelSelector=S][&fieldSelector=S][&preset=P]
+// GET kube/v1/contexts/<ctx>/export/resources/<group>/<version>/namespaces/<ns>/<resource>[?format=...][&labelSelector=S][&fieldSelector=S][&preset=P]
+func (h *Handler) Resources(c *gin.Context) {
+	logger := h.Logger(c).
+		WithField("method", "Resources").
+		WithField("context", c.Param("ctx")).
+		WithField("group", c.Param("group")).
+		WithField("version", c.Param("version")).
+		WithField("resource", c.Param("resource")).
+		WithField("namespace", c.Param("namespace")).
+		WithField("preset", c.Query("preset")).
+		WithField("format", c.Query("format"))
+
+	format, ok := parseFormat(c)
+	if !ok {
+		return
+	}
+
+	kctx, src, preset, ok := h.prepare(c, logger)
+	if !ok {
+		return
+	}
+
+	gvk, ok := mapResource(c, logger, src)
+	if !ok {
+		return
+	}
+
+	client, err := kctx.DynamicClient()
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't get Kubernetes client for context")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	list, err := client.
+		Resource(gvk.GroupVersion().WithResource(c.Param("resource"))).
+		Namespace(c.Param("namespace")).
+		List(c.Request.Context(), metav1.ListOptions{
+			FieldSelector: c.Query("fieldSelector"),
+			LabelSelector: c.Query("labelSelector"),
+		})
+	if err != nil {
+		if apierrors.IsForbidden(err) {
+			c.AbortWithStatusJSON(
+				http.StatusForbidden,
+				map[string]string{"error": "forbidden"},
+			)
+			return
+		}
+
+		logger.
+			WithError(err).
+			Error("Couldn't list objects")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	objs := []*unstructured.Unstructured{}
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+
+	h.write(c, logger, preset.Engine(src).Connect(objs), format)
+}
+
+func (h *Handler) prepare(
+	c *gin.Context,
+	logger *logrus.Entry,
+) (*kubeclient.Context, *relations.CacheSource, relations.Preset, bool) {
+	kctx, err := h.clientPool.ContextFor(c.Request.Context(), c.Param("ctx"))
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Unknown context")
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "unknown context"},
+		)
+		return nil, nil, relations.Preset{}, false
+	}
+
+	preset, err := h.registry.Lookup(c.Request.Context(), kctx, c.Query("preset"))
+	if err != nil {
+		if errors.Is(err, presets.ErrNotFound) {
+			c.AbortWithStatusJSON(
+				http.StatusNotFound,
+				map[string]string{"error": "unknown preset"},
+			)
+			return nil, nil, relations.Preset{}, false
+		}
+
+		logger.
+			WithError(err).
+			Error("Couldn't look up preset")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return nil, nil, relations.Preset{}, false
+	}
+
+	src, err := relations.NewCacheSource(kctx)
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't get Kubernetes client for context")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return nil, nil, relations.Preset{}, false
+	}
+
+	return kctx, src, preset, true
+}
+
+func (h *Handler) write(c *gin.Context, logger *logrus.Entry, graph *relations.Graph, format relations.ExportFormat) {
+	var buf bytes.Buffer
+	if err := relations.Export(&buf, graph, format); err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't export graph")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	c.Data(http.StatusOK, format.ContentType()+"; charset=utf-8", buf.Bytes())
+}
+
+func parseFormat(c *gin.Context) (relations.ExportFormat, bool) {
+	if c.Query("format") == "" {
+		return relations.FormatDOT, true
+	}
+
+	format, err := relations.ParseExportFormat(c.Query("format"))
+	if err != nil {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			map[string]string{"error": "invalid format"},
+		)
+		return "", false
+	}
+	return format, true
+}
+
+func mapResource(c *gin.Context, logger *logrus.Entry, src *relations.CacheSource) (schema.GroupVersionKind, bool) {
+	group := c.Param("group")
+	if group == "core" {
+		group = ""
+	}
+
+	gvk, err := src.Mapper().KindFor(schema.GroupVersionResource{
+		Group:    group,
+		Version:  c.Param("version"),
+		Resource: c.Param("resource"),
+	})
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			c.AbortWithStatusJSON(
+				http.StatusNotFound,
+				map[string]string{"error": "unknown resource"},
+			)
+			return schema.GroupVersionKind{}, false
+		}
+
+		logger.
+			WithError(err).
+			Error("Couldn't map resource to kind")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return schema.GroupVersionKind{}, false
+	}
+	return gvk, true
+}
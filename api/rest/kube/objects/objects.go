@@ -14,19 +14,28 @@ import (
 	"k8s.io/client-go/dynamic"
 
 	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/audit"
 	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/logging"
+	"github.com/iximiuz/kexp/policy"
 )
 
 type Handler struct {
 	api.Handler
 
 	clientPool *kubeclient.ClientPool
+	auditLog   *audit.Log
 }
 
-func NewHandler(clientPool *kubeclient.ClientPool, logger *logrus.Entry) *Handler {
+func NewHandler(
+	clientPool *kubeclient.ClientPool,
+	auditLog *audit.Log,
+	logger *logrus.Entry,
+) *Handler {
 	return &Handler{
 		Handler:    api.NewHandler("kube/objects", logger),
 		clientPool: clientPool,
+		auditLog:   auditLog,
 	}
 }
 
@@ -69,6 +78,14 @@ func (h *Handler) Get(c *gin.Context) {
 			return
 		}
 
+		if apierrors.IsForbidden(err) {
+			c.AbortWithStatusJSON(
+				http.StatusForbidden,
+				map[string]string{"error": "forbidden"},
+			)
+			return
+		}
+
 		logger.
 			WithError(err).
 			Error("Couldn't get Kubernetes object")
@@ -119,6 +136,14 @@ func (h *Handler) List(c *gin.Context) {
 			LabelSelector: c.Query("labelSelector"),
 		})
 	if err != nil {
+		if apierrors.IsForbidden(err) {
+			c.AbortWithStatusJSON(
+				http.StatusForbidden,
+				map[string]string{"error": "forbidden"},
+			)
+			return
+		}
+
 		logger.
 			WithError(err).
 			Error("Couldn't list Kubernetes objects")
@@ -159,15 +184,28 @@ func (h *Handler) Update(c *gin.Context) {
 		return
 	}
 
-	obj, err = client.
+	resClient := client.
 		Resource(schema.GroupVersionResource{
 			Group:    group,
 			Version:  c.Param("version"),
 			Resource: c.Param("resource"),
 		}).
-		Namespace(c.Param("namespace")).
-		Update(c.Request.Context(), obj, metav1.UpdateOptions{})
+		Namespace(c.Param("namespace"))
+
+	// Best effort - only to audit the changes.
+	before, _ := resClient.Get(c.Request.Context(), c.Param("name"), metav1.GetOptions{})
+
+	obj, err = resClient.Update(c.Request.Context(), obj, metav1.UpdateOptions{})
+	h.audit(c, policy.VerbUpdate, before, obj, err, logger)
 	if err != nil && !apierrors.IsNotFound(err) {
+		if apierrors.IsForbidden(err) {
+			c.AbortWithStatusJSON(
+				http.StatusForbidden,
+				map[string]string{"error": "forbidden"},
+			)
+			return
+		}
+
 		logger.
 			WithError(err).
 			Error("Couldn't update Kubernetes object")
@@ -203,15 +241,28 @@ func (h *Handler) Delete(c *gin.Context) {
 		return
 	}
 
-	err = client.
+	resClient := client.
 		Resource(schema.GroupVersionResource{
 			Group:    group,
 			Version:  c.Param("version"),
 			Resource: c.Param("resource"),
 		}).
-		Namespace(c.Param("namespace")).
-		Delete(c.Request.Context(), c.Param("name"), metav1.DeleteOptions{})
+		Namespace(c.Param("namespace"))
+
+	// Best effort - only to audit the changes.
+	before, _ := resClient.Get(c.Request.Context(), c.Param("name"), metav1.GetOptions{})
+
+	err = resClient.Delete(c.Request.Context(), c.Param("name"), metav1.DeleteOptions{})
+	h.audit(c, policy.VerbDelete, before, nil, err, logger)
 	if err != nil && !apierrors.IsNotFound(err) {
+		if apierrors.IsForbidden(err) {
+			c.AbortWithStatusJSON(
+				http.StatusForbidden,
+				map[string]string{"error": "forbidden"},
+			)
+			return
+		}
+
 		logger.
 			WithError(err).
 			Error("Couldn't delete Kubernetes object")
@@ -225,11 +276,57 @@ func (h *Handler) Delete(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// audit records the action (successful or not) in the audit log.
+func (h *Handler) audit(
+	c *gin.Context,
+	verb string,
+	before *unstructured.Unstructured,
+	after *unstructured.Unstructured,
+	err error,
+	logger *logrus.Entry,
+) {
+	group := c.Param("group")
+	if group == "core" {
+		group = ""
+	}
+
+	entry := audit.Entry{
+		RequestID:  logging.RequestID(c.Request.Context()),
+		Principal:  logging.Principal(c.Request.Context()),
+		ClientAddr: c.ClientIP(),
+		RemoteAddr: c.Request.RemoteAddr,
+		Verb:       verb,
+		Context:    c.Param("ctx"),
+		Group:      group,
+		Version:    c.Param("version"),
+		Resource:   c.Param("resource"),
+		Namespace:  c.Param("namespace"),
+		Name:       c.Param("name"),
+		Outcome:    audit.OutcomeSucceeded,
+	}
+	if imp, ok := kubeclient.ImpersonationFrom(c.Request.Context()); ok {
+		entry.ImpersonatedUser = imp.User
+		entry.ImpersonatedGroups = imp.Groups
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailed
+		entry.Error = err.Error()
+	} else {
+		entry.Changes = audit.Changes(before, after)
+	}
+
+	if err := h.auditLog.Append(entry); err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't write audit log entry")
+	}
+}
+
 func (h *Handler) kubeClient(
 	c *gin.Context,
 	logger *logrus.Entry,
 ) (dynamic.Interface, error) {
-	kctx, err := h.clientPool.Context(c.Param("ctx"))
+	kctx, err := h.clientPool.ContextFor(c.Request.Context(), c.Param("ctx"))
 	if err != nil {
 		logger.
 			WithError(err).
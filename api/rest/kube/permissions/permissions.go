@@ -0,0 +1,287 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+// Verbs the matrix is computed for (if supported by the resource).
+var verbs = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+
+// Max number of SelfSubjectAccessReviews in flight.
+const accessReviewConcurrency = 16
+
+type Handler struct {
+	api.Handler
+
+	clientPool *kubeclient.ClientPool
+}
+
+func NewHandler(clientPool *kubeclient.ClientPool, logger *logrus.Entry) *Handler {
+	return &Handler{
+		Handler:    api.NewHandler("kube/permissions", logger),
+		clientPool: clientPool,
+	}
+}
+
+// GET kube/v1/contexts/<ctx>/permissions?namespace=<ns>
+func (h *Handler) List(c *gin.Context) {
+	logger := h.Logger(c).
+		WithField("method", "List").
+		WithField("context", c.Param("ctx")).
+		WithField("namespace", c.Query("namespace"))
+
+	kctx, err := h.clientPool.ContextFor(c.Request.Context(), c.Param("ctx"))
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Unknown context")
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "unknown context"},
+		)
+		return
+	}
+
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		namespace = kctx.Namespace()
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	discoveryClient, err := kctx.DiscoveryClient()
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't get Kubernetes client for context")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	clientset, err := kctx.Clientset()
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't get Kubernetes client for context")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	resourceLists, err := discoveryClient.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		logger.
+			WithError(err).
+			Error("Couldn't load Kubernetes preferred resources")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	review, err := clientset.AuthorizationV1().SelfSubjectRulesReviews().Create(
+		c.Request.Context(),
+		&authorizationv1.SelfSubjectRulesReview{
+			Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't review access rules")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	matrix := Matrix{
+		Namespace:       namespace,
+		Incomplete:      review.Status.Incomplete,
+		EvaluationError: review.Status.EvaluationError,
+		Resources:       []ResourcePermissions{},
+	}
+
+	// Rules are evaluated locally first. Whatever can't be decided
+	// with certainty is double-checked with SelfSubjectAccessReviews.
+	var pending []accessCheck
+
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") {
+				continue // Subresources aren't a part of the matrix.
+			}
+
+			perms := ResourcePermissions{
+				Group:      gv.Group,
+				Version:    gv.Version,
+				Resource:   res.Name,
+				Kind:       res.Kind,
+				Namespaced: res.Namespaced,
+				Verbs:      map[string]bool{},
+			}
+
+			for _, verb := range verbs {
+				if !slices.Contains(res.Verbs, verb) {
+					continue
+				}
+
+				allowed := rulesAllow(review.Status.ResourceRules, gv.Group, res.Name, verb)
+				perms.Verbs[verb] = allowed
+
+				// Cluster-wide rules and namespaced rules are indistinguishable
+				// in the review, so grants of cluster-scoped resources need a check.
+				if (!allowed && review.Status.Incomplete) || (allowed && !res.Namespaced) {
+					pending = append(pending, accessCheck{
+						index:    len(matrix.Resources),
+						group:    gv.Group,
+						version:  gv.Version,
+						resource: res.Name,
+						verb:     verb,
+					})
+				}
+			}
+
+			matrix.Resources = append(matrix.Resources, perms)
+		}
+	}
+
+	if err := reviewAccess(c.Request.Context(), clientset, namespace, pending, &matrix); err != nil {
+		logger.
+			WithError(err).
+			Warn("Couldn't review access for some resources")
+		matrix.Incomplete = true
+	}
+
+	c.JSON(http.StatusOK, matrix)
+}
+
+type Matrix struct {
+	Namespace       string                `json:"namespace"`
+	Incomplete      bool                  `json:"incomplete"`
+	EvaluationError string                `json:"evaluationError,omitempty"`
+	Resources       []ResourcePermissions `json:"resources"`
+}
+
+type ResourcePermissions struct {
+	Group      string          `json:"group"`
+	Version    string          `json:"version"`
+	Resource   string          `json:"resource"`
+	Kind       string          `json:"kind"`
+	Namespaced bool            `json:"namespaced"`
+	Verbs      map[string]bool `json:"verbs"`
+}
+
+type accessCheck struct {
+	index    int
+	group    string
+	version  string
+	resource string
+	verb     string
+}
+
+func reviewAccess(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	namespace string,
+	checks []accessCheck,
+	matrix *Matrix,
+) error {
+	var (
+		wg   sync.WaitGroup
+		mux  sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, accessReviewConcurrency)
+	)
+
+	for _, check := range checks {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			attrs := &authorizationv1.ResourceAttributes{
+				Group:    check.group,
+				Version:  check.version,
+				Resource: check.resource,
+				Verb:     check.verb,
+			}
+			if matrix.Resources[check.index].Namespaced {
+				attrs.Namespace = namespace
+			}
+
+			review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(
+				ctx,
+				&authorizationv1.SelfSubjectAccessReview{
+					Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+				},
+				metav1.CreateOptions{},
+			)
+
+			mux.Lock()
+			defer mux.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			matrix.Resources[check.index].Verbs[check.verb] = review.Status.Allowed
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func rulesAllow(rules []authorizationv1.ResourceRule, group, resource, verb string) bool {
+	for _, rule := range rules {
+		// Rules limited to particular objects don't grant access to the whole resource.
+		if len(rule.ResourceNames) > 0 {
+			continue
+		}
+
+		if matches(rule.APIGroups, group) &&
+			matches(rule.Resources, resource) &&
+			matches(rule.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(values []string, value string) bool {
+	return slices.Contains(values, "*") || slices.Contains(values, value)
+}
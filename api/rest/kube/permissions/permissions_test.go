@@ -0,0 +1,48 @@
+package permissions
+
+import (
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+func TestRulesAllow(t *testing.T) {
+	rules := []authorizationv1.ResourceRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "services"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"*"}, Verbs: []string{"get"}},
+		{APIGroups: []string{"*"}, Resources: []string{"configmaps"}, Verbs: []string{"*"}},
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}, ResourceNames: []string{"db"}},
+		{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"create"}},
+		{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"delete"}},
+	}
+
+	tests := []struct {
+		name     string
+		rules    []authorizationv1.ResourceRule
+		group    string
+		resource string
+		verb     string
+		want     bool
+	}{
+		{"exact match", rules, "", "pods", "list", true},
+		{"other verb", rules, "", "pods", "delete", false},
+		{"core rule in another group", rules, "apps", "pods", "list", false},
+		{"resource wildcard", rules, "apps", "deployments", "get", true},
+		{"resource wildcard, other verb", rules, "apps", "deployments", "update", false},
+		{"group and verb wildcards", rules, "example.com", "configmaps", "deletecollection", true},
+		{"resourceNames don't grant the whole resource", rules, "", "secrets", "get", false},
+		{"verbs from separate rules", rules, "batch", "jobs", "delete", true},
+		{"group name isn't a prefix", rules, "apps.example.com", "deployments", "get", false},
+		{"everything", []authorizationv1.ResourceRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}}, "apps", "deployments", "patch", true},
+		{"no rules", nil, "", "pods", "get", false},
+		{"rule without verbs", []authorizationv1.ResourceRule{{APIGroups: []string{""}, Resources: []string{"pods"}}}, "", "pods", "get", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rulesAllow(tt.rules, tt.group, tt.resource, tt.verb); got != tt.want {
+				t.Errorf("rulesAllow(%q, %q, %q) = %v, want %v", tt.group, tt.resource, tt.verb, got, tt.want)
+			}
+		})
+	}
+}
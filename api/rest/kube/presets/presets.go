@@ -0,0 +1,69 @@
+package presets
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/presets"
+)
+
+type Handler struct {
+	api.Handler
+
+	clientPool *kubeclient.ClientPool
+	registry   *presets.Registry
+}
+
+func NewHandler(
+	clientPool *kubeclient.ClientPool,
+	registry *presets.Registry,
+	logger *logrus.Entry,
+) *Handler {
+	return &Handler{
+		Handler:    api.NewHandler("kube/presets", logger),
+		clientPool: clientPool,
+		registry:   registry,
+	}
+}
+
+// GET kube/v1/contexts/<ctx>/presets
+func (h *Handler) List(c *gin.Context) {
+	logger := h.Logger(c).
+		WithField("method", "List").
+		WithField("context", c.Param("ctx"))
+
+	kctx, err := h.clientPool.ContextFor(c.Request.Context(), c.Param("ctx"))
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Unknown context")
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "unknown context"},
+		)
+		return
+	}
+
+	entries, err := h.registry.List(c.Request.Context(), kctx)
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't list presets")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	defs := []presets.Definition{}
+	for _, entry := range entries {
+		defs = append(defs, entry.Definition)
+	}
+
+	c.JSON(http.StatusOK, defs)
+}
@@ -0,0 +1,164 @@
+package relations
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/presets"
+	"github.com/iximiuz/kexp/relations"
+)
+
+type Handler struct {
+	api.Handler
+
+	clientPool *kubeclient.ClientPool
+	registry   *presets.Registry
+}
+
+func NewHandler(
+	clientPool *kubeclient.ClientPool,
+	registry *presets.Registry,
+	logger *logrus.Entry,
+) *Handler {
+	return &Handler{
+		Handler:    api.NewHandler("kube/relations", logger),
+		clientPool: clientPool,
+		registry:   registry,
+	}
+}
+
+// GET kube/v1/contexts/<ctx>/relations/<group>/<version>/<resource>/<name>[?depth=N][&preset=P]
+// GET kube/v1/contexts/<ctx>/relations/<group>/<version>/namespaces/<ns>/<resource>/<name>[?depth=N][&preset=P]
+func (h *Handler) Get(c *gin.Context) {
+	logger := h.Logger(c).
+		WithField("method", "Get").
+		WithField("context", c.Param("ctx")).
+		WithField("group", c.Param("group")).
+		WithField("version", c.Param("version")).
+		WithField("resource", c.Param("resource")).
+		WithField("namespace", c.Param("namespace")).
+		WithField("name", c.Param("name")).
+		WithField("preset", c.Query("preset"))
+
+	group := c.Param("group")
+	if group == "core" {
+		group = ""
+	}
+
+	depth := relations.DefaultDepth
+	if c.Query("depth") != "" {
+		d, err := strconv.Atoi(c.Query("depth"))
+		if err != nil || d < 0 || d > relations.MaxDepth {
+			c.AbortWithStatusJSON(
+				http.StatusBadRequest,
+				map[string]string{"error": "invalid depth"},
+			)
+			return
+		}
+		depth = d
+	}
+
+	kctx, err := h.clientPool.ContextFor(c.Request.Context(), c.Param("ctx"))
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Unknown context")
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "unknown context"},
+		)
+		return
+	}
+
+	preset, err := h.registry.Lookup(c.Request.Context(), kctx, c.Query("preset"))
+	if err != nil {
+		if errors.Is(err, presets.ErrNotFound) {
+			c.AbortWithStatusJSON(
+				http.StatusNotFound,
+				map[string]string{"error": "unknown preset"},
+			)
+			return
+		}
+
+		logger.
+			WithError(err).
+			Error("Couldn't look up preset")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	src, err := relations.NewCacheSource(kctx)
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't get Kubernetes client for context")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	gvk, err := src.Mapper().KindFor(schema.GroupVersionResource{
+		Group:    group,
+		Version:  c.Param("version"),
+		Resource: c.Param("resource"),
+	})
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			c.AbortWithStatusJSON(
+				http.StatusNotFound,
+				map[string]string{"error": "unknown resource"},
+			)
+			return
+		}
+
+		logger.
+			WithError(err).
+			Error("Couldn't map resource to kind")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	graph, err := preset.Engine(src).Related(c.Request.Context(), relations.ObjectRef{
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Namespace: c.Param("namespace"),
+		Name:      c.Param("name"),
+	}, depth)
+	if err != nil {
+		if errors.Is(err, relations.ErrNotFound) {
+			c.AbortWithStatusJSON(
+				http.StatusNotFound,
+				map[string]string{"error": "not found"},
+			)
+			return
+		}
+
+		logger.
+			WithError(err).
+			Error("Couldn't compute related objects")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, graph)
+}
@@ -1,15 +1,35 @@
 package resources
 
 import (
+	"errors"
 	"net/http"
+	"sort"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 
 	"github.com/iximiuz/kexp/api"
 	"github.com/iximiuz/kexp/kubeclient"
 )
 
+const (
+	// Only the preferred version of every resource (like kubectl api-resources).
+	modePreferred = "preferred"
+
+	// Every served group/version with its resources.
+	modeAll = "all"
+)
+
+var crdResource = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
 type Handler struct {
 	api.Handler
 
@@ -23,13 +43,23 @@ func NewHandler(clientPool *kubeclient.ClientPool, logger *logrus.Entry) *Handle
 	}
 }
 
-// kube/v1/contexts/<ctx>/resources
+// kube/v1/contexts/<ctx>/resources[?mode=preferred|all]
 func (h *Handler) List(c *gin.Context) {
 	logger := h.Logger(c).
 		WithField("method", "List").
-		WithField("context", c.Param("ctx"))
+		WithField("context", c.Param("ctx")).
+		WithField("mode", c.Query("mode"))
+
+	mode := c.DefaultQuery("mode", modePreferred)
+	if mode != modePreferred && mode != modeAll {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			map[string]string{"error": "unknown discovery mode"},
+		)
+		return
+	}
 
-	kctx, err := h.clientPool.Context(c.Param("ctx"))
+	kctx, err := h.clientPool.ContextFor(c.Request.Context(), c.Param("ctx"))
 	if err != nil {
 		logger.
 			WithError(err).
@@ -53,8 +83,23 @@ func (h *Handler) List(c *gin.Context) {
 		return
 	}
 
-	resourceList, err := client.ServerPreferredResources()
-	if err != nil {
+	if mode == modeAll {
+		h.listAll(c, logger, kctx, client)
+	} else {
+		h.listPreferred(c, logger, client)
+	}
+}
+
+func (h *Handler) listPreferred(
+	c *gin.Context,
+	logger *logrus.Entry,
+	client discovery.DiscoveryInterface,
+) {
+	// A single unavailable (aggregated) API group should not
+	// make all the other resources unavailable.
+	resourceLists, err := client.ServerPreferredResources()
+	failedGroups, ok := failedGroupsFromError(err)
+	if !ok {
 		logger.
 			WithError(err).
 			Error("Couldn't load Kubernetes preferred resources")
@@ -64,6 +109,207 @@ func (h *Handler) List(c *gin.Context) {
 		)
 		return
 	}
+	if err != nil {
+		logger.
+			WithError(err).
+			Warn("Couldn't load some of Kubernetes preferred resources")
+	}
+
+	c.JSON(http.StatusOK, ResourceList{
+		Resources:    append([]*metav1.APIResourceList{}, resourceLists...),
+		FailedGroups: failedGroups,
+	})
+}
+
+func (h *Handler) listAll(
+	c *gin.Context,
+	logger *logrus.Entry,
+	kctx *kubeclient.Context,
+	client discovery.DiscoveryInterface,
+) {
+	groups, resourceLists, err := client.ServerGroupsAndResources()
+	failedGroups, ok := failedGroupsFromError(err)
+	if !ok {
+		logger.
+			WithError(err).
+			Error("Couldn't load Kubernetes groups and resources")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+	if err != nil {
+		logger.
+			WithError(err).
+			Warn("Couldn't load some of Kubernetes groups and resources")
+	}
+
+	storageVersions := h.crdStorageVersions(c, logger, kctx)
+
+	byGroupVersion := map[string]*metav1.APIResourceList{}
+	for _, list := range resourceLists {
+		byGroupVersion[list.GroupVersion] = list
+	}
+
+	resp := GroupList{
+		Groups:       []Group{},
+		FailedGroups: failedGroups,
+	}
+
+	for _, g := range groups {
+		group := Group{
+			Name:             g.Name,
+			PreferredVersion: g.PreferredVersion.Version,
+			Versions:         []GroupVersion{},
+		}
+
+		for _, v := range g.Versions {
+			list, found := byGroupVersion[v.GroupVersion]
+			if !found {
+				continue // Discovery of this group version failed.
+			}
+
+			version := GroupVersion{
+				GroupVersion: v.GroupVersion,
+				Version:      v.Version,
+				Preferred:    v.Version == g.PreferredVersion.Version,
+				Resources:    []Resource{},
+			}
+
+			for _, r := range list.APIResources {
+				version.Resources = append(version.Resources, Resource{
+					Name:               r.Name,
+					SingularName:       r.SingularName,
+					Kind:               r.Kind,
+					Namespaced:         r.Namespaced,
+					Verbs:              r.Verbs,
+					ShortNames:         r.ShortNames,
+					Categories:         r.Categories,
+					StorageVersion:     storageVersions[schema.GroupResource{Group: g.Name, Resource: r.Name}],
+					StorageVersionHash: r.StorageVersionHash,
+				})
+			}
+
+			group.Versions = append(group.Versions, version)
+		}
+
+		resp.Groups = append(resp.Groups, group)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Storage versions are known only for custom resources - built-in
+// resources expose merely an opaque storage version hash. The CRDs
+// are served from the context's shared informer cache.
+func (h *Handler) crdStorageVersions(
+	c *gin.Context,
+	logger *logrus.Entry,
+	kctx *kubeclient.Context,
+) map[schema.GroupResource]string {
+	versions := map[schema.GroupResource]string{}
+
+	crds, err := kctx.Store(c.Request.Context(), crdResource, "")
+	if err != nil {
+		logger.
+			WithError(err).
+			Debug("Couldn't list CRDs - storage versions of custom resources are unknown")
+		return versions
+	}
+	if crds == nil {
+		logger.Debug("Not allowed to list CRDs - storage versions of custom resources are unknown")
+		return versions
+	}
+
+	for _, obj := range crds.List() {
+		crd, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+		specVersions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+
+		for _, v := range specVersions {
+			v, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if storage, _, _ := unstructured.NestedBool(v, "storage"); storage {
+				name, _, _ := unstructured.NestedString(v, "name")
+				versions[schema.GroupResource{Group: group, Resource: plural}] = name
+			}
+		}
+	}
+
+	return versions
+}
+
+// Partial discovery failures are reported alongside the results.
+// Returns false if the error isn't a partial discovery failure.
+func failedGroupsFromError(err error) ([]FailedGroup, bool) {
+	failedGroups := []FailedGroup{}
+	if err == nil {
+		return failedGroups, true
+	}
+
+	var failed *discovery.ErrGroupDiscoveryFailed
+	if !errors.As(err, &failed) {
+		return nil, false
+	}
+
+	for gv, err := range failed.Groups {
+		failedGroups = append(failedGroups, FailedGroup{
+			GroupVersion: gv.String(),
+			Error:        err.Error(),
+		})
+	}
+	sort.Slice(failedGroups, func(i, j int) bool {
+		return failedGroups[i].GroupVersion < failedGroups[j].GroupVersion
+	})
+
+	return failedGroups, true
+}
+
+type ResourceList struct {
+	Resources    []*metav1.APIResourceList `json:"resources"`
+	FailedGroups []FailedGroup             `json:"failedGroups"`
+}
+
+type GroupList struct {
+	Groups       []Group       `json:"groups"`
+	FailedGroups []FailedGroup `json:"failedGroups"`
+}
+
+type Group struct {
+	Name             string         `json:"name"`
+	PreferredVersion string         `json:"preferredVersion"`
+	Versions         []GroupVersion `json:"versions"`
+}
+
+type GroupVersion struct {
+	GroupVersion string     `json:"groupVersion"`
+	Version      string     `json:"version"`
+	Preferred    bool       `json:"preferred"`
+	Resources    []Resource `json:"resources"`
+}
+
+type Resource struct {
+	Name               string   `json:"name"`
+	SingularName       string   `json:"singularName"`
+	Kind               string   `json:"kind"`
+	Namespaced         bool     `json:"namespaced"`
+	Verbs              []string `json:"verbs"`
+	ShortNames         []string `json:"shortNames,omitempty"`
+	Categories         []string `json:"categories,omitempty"`
+	StorageVersion     string   `json:"storageVersion,omitempty"`
+	StorageVersionHash string   `json:"storageVersionHash,omitempty"`
+}
 
-	c.JSON(http.StatusOK, resourceList)
+type FailedGroup struct {
+	GroupVersion string `json:"groupVersion"`
+	Error        string `json:"error"`
 }
@@ -0,0 +1,213 @@
+package schemas
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+const (
+	extensionGVK = "x-kubernetes-group-version-kind"
+
+	refPrefix = "#/components/schemas/"
+)
+
+type Handler struct {
+	api.Handler
+
+	clientPool *kubeclient.ClientPool
+}
+
+func NewHandler(clientPool *kubeclient.ClientPool, logger *logrus.Entry) *Handler {
+	return &Handler{
+		Handler:    api.NewHandler("kube/schemas", logger),
+		clientPool: clientPool,
+	}
+}
+
+// GET kube/v1/contexts/<ctx>/schemas/<group>/<version>/<kind>
+func (h *Handler) Get(c *gin.Context) {
+	logger := h.Logger(c).
+		WithField("method", "Get").
+		WithField("context", c.Param("ctx")).
+		WithField("group", c.Param("group")).
+		WithField("version", c.Param("version")).
+		WithField("kind", c.Param("kind"))
+
+	gvk := schema.GroupVersionKind{
+		Group:   c.Param("group"),
+		Version: c.Param("version"),
+		Kind:    c.Param("kind"),
+	}
+	if gvk.Group == "core" {
+		gvk.Group = ""
+	}
+
+	kctx, err := h.clientPool.ContextFor(c.Request.Context(), c.Param("ctx"))
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Unknown context")
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "unknown context"},
+		)
+		return
+	}
+
+	client, err := kctx.DiscoveryClient()
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't get Kubernetes client for context")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	paths, err := client.OpenAPIV3().Paths()
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't load OpenAPI v3 paths")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	gvPath := "apis/" + gvk.Group + "/" + gvk.Version
+	if gvk.Group == "" {
+		gvPath = "api/" + gvk.Version
+	}
+
+	gv, found := paths[gvPath]
+	if !found {
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "not found"},
+		)
+		return
+	}
+
+	raw, err := gv.Schema(runtime.ContentTypeJSON)
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't load OpenAPI v3 schema")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	doc := struct {
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't decode OpenAPI v3 schema")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	name, found := findKindSchema(doc.Components.Schemas, gvk)
+	if !found {
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "not found"},
+		)
+		return
+	}
+
+	definitions := map[string]interface{}{}
+	collectReferences(doc.Components.Schemas, doc.Components.Schemas[name], definitions)
+
+	c.JSON(http.StatusOK, Schema{
+		Group:       gvk.Group,
+		Version:     gvk.Version,
+		Kind:        gvk.Kind,
+		Name:        name,
+		Schema:      doc.Components.Schemas[name],
+		Definitions: definitions,
+	})
+}
+
+// Schema of a kind. The $ref-s in the schema (and in the definitions)
+// point to #/components/schemas/<name> and are to be resolved against
+// the definitions map.
+type Schema struct {
+	Group       string                 `json:"group"`
+	Version     string                 `json:"version"`
+	Kind        string                 `json:"kind"`
+	Name        string                 `json:"name"`
+	Schema      interface{}            `json:"schema"`
+	Definitions map[string]interface{} `json:"definitions"`
+}
+
+func findKindSchema(schemas map[string]interface{}, gvk schema.GroupVersionKind) (string, bool) {
+	for name, s := range schemas {
+		s, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		gvks, _ := s[extensionGVK].([]interface{})
+		for _, v := range gvks {
+			v, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if v["group"] == gvk.Group && v["version"] == gvk.Version && v["kind"] == gvk.Kind {
+				return name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// collectReferences walks the schema and copies all (transitively)
+// referenced schemas into the definitions map.
+func collectReferences(schemas map[string]interface{}, node interface{}, definitions map[string]interface{}) {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := node["$ref"].(string); ok && strings.HasPrefix(ref, refPrefix) {
+			name := strings.TrimPrefix(ref, refPrefix)
+			if _, seen := definitions[name]; !seen {
+				if def, found := schemas[name]; found {
+					definitions[name] = def
+					collectReferences(schemas, def, definitions)
+				}
+			}
+		}
+
+		for _, v := range node {
+			collectReferences(schemas, v, definitions)
+		}
+
+	case []interface{}:
+		for _, v := range node {
+			collectReferences(schemas, v, definitions)
+		}
+	}
+}
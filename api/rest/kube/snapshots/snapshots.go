@@ -0,0 +1,109 @@
+package snapshots
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/snapshot"
+)
+
+// Context names are often ARNs and alike.
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type Handler struct {
+	api.Handler
+
+	clientPool *kubeclient.ClientPool
+}
+
+func NewHandler(clientPool *kubeclient.ClientPool, logger *logrus.Entry) *Handler {
+	return &Handler{
+		Handler:    api.NewHandler("kube/snapshots", logger),
+		clientPool: clientPool,
+	}
+}
+
+// Responds with a snapshot tarball (see the snapshot package) of the selected objects.
+// An empty body selects all objects of the cluster.
+//
+// POST kube/v1/contexts/<ctx>/snapshots
+func (h *Handler) Create(c *gin.Context) {
+	logger := h.Logger(c).
+		WithField("method", "Create").
+		WithField("context", c.Param("ctx"))
+
+	sel := snapshot.Selection{}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&sel); err != nil {
+			logger.
+				WithError(err).
+				Warn("Couldn't decode snapshot selection")
+			c.AbortWithStatusJSON(
+				http.StatusBadRequest,
+				map[string]string{"error": "invalid selection"},
+			)
+			return
+		}
+	}
+
+	kctx, err := h.clientPool.ContextFor(c.Request.Context(), c.Param("ctx"))
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Unknown context")
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "unknown context"},
+		)
+		return
+	}
+
+	snap, err := snapshot.Capture(c.Request.Context(), kctx, sel, logger)
+	if err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't capture snapshot")
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	filename := fmt.Sprintf(
+		"kexp-%s-%s.tar.gz",
+		unsafeFilenameChars.ReplaceAllString(kctx.Name(), "_"),
+		time.Now().UTC().Format("20060102-150405"),
+	)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "application/gzip")
+	c.Status(http.StatusOK)
+
+	// The archive is streamed - once it's started, the status can't be
+	// changed anymore, so a failure just cuts the (invalid) archive short.
+	if err := snapshot.Write(c.Writer, snap); err != nil {
+		logger.
+			WithError(err).
+			Error("Couldn't write snapshot")
+		if c.Writer.Written() {
+			c.Abort()
+			return
+		}
+		c.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			map[string]string{"error": "internal server error"},
+		)
+		return
+	}
+
+	logger.
+		WithField("objects", len(snap.Objects)).
+		Info("Snapshot captured")
+}
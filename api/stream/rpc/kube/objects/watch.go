@@ -21,6 +21,7 @@ import (
 	"github.com/iximiuz/kexp/api/stream/rpc"
 	"github.com/iximiuz/kexp/kubeclient"
 	"github.com/iximiuz/kexp/logging"
+	"github.com/iximiuz/kexp/recording"
 )
 
 const Watch rpc.CallMethod = "kubeObjects.watch"
@@ -38,12 +39,16 @@ type paramsWatch struct {
 
 type WatchHandler struct {
 	clientPool *kubeclient.ClientPool
+	recorder   *recording.Recorder
 	logger     *logrus.Entry
 }
 
-func NewWatchHandler(clientPool *kubeclient.ClientPool) *WatchHandler {
+// NewWatchHandler creates a watch handler. If the recorder is not nil,
+// every watch event is also written to it.
+func NewWatchHandler(clientPool *kubeclient.ClientPool, recorder *recording.Recorder) *WatchHandler {
 	return &WatchHandler{
 		clientPool: clientPool,
+		recorder:   recorder,
 		logger:     logrus.WithField("handler", "stream/rpc/kube/objects/watch"),
 	}
 }
@@ -71,11 +76,26 @@ func (h *WatchHandler) Handle(ctx context.Context, call rpc.Call, reply chan<- s
 	logger = logger.WithField("callParams", &params)
 	logger.Debug("Handling RPC call")
 
-	kctx, err := h.clientPool.Context(params.Context)
+	kctx, err := h.clientPool.ContextFor(ctx, params.Context)
 	if err != nil {
 		return err
 	}
 
+	return inform(ctx, kctx, params, logger, func(un *unstructured.Unstructured, event recording.EventType) {
+		record(h.recorder, kctx, params, un, event, logger)
+		reply <- encodeResponse(call, un, string(event), nil)
+	})
+}
+
+// inform runs an informer for the watch params and calls the handler
+// on every object event until the ctx is done.
+func inform(
+	ctx context.Context,
+	kctx *kubeclient.Context,
+	params paramsWatch,
+	logger *logrus.Entry,
+	handler func(un *unstructured.Unstructured, event recording.EventType),
+) error {
 	kubeClient, err := kctx.DynamicClient()
 	if err != nil {
 		return err
@@ -103,6 +123,7 @@ func (h *WatchHandler) Handle(ctx context.Context, call rpc.Call, reply chan<- s
 	})
 
 	factory.Start(ctx.Done())
+	defer factory.Shutdown() // Waits for the informer goroutines to exit.
 	for _, ok := range factory.WaitForCacheSync(ctx.Done()) {
 		if !ok {
 			// The context has been canceled - not much we can do here.
@@ -123,7 +144,7 @@ func (h *WatchHandler) Handle(ctx context.Context, call rpc.Call, reply chan<- s
 				WithField("objectNamespace", un.GetNamespace()).
 				Trace("Informer event")
 
-			reply <- encodeResponse(call, obj.(runtime.Object), "added", nil)
+			handler(un, recording.EventAdded)
 		},
 		UpdateFunc: func(_, newObj interface{}) {
 			un, ok := newObj.(*unstructured.Unstructured)
@@ -137,7 +158,7 @@ func (h *WatchHandler) Handle(ctx context.Context, call rpc.Call, reply chan<- s
 				WithField("objectNamespace", un.GetNamespace()).
 				Trace("Informer event")
 
-			reply <- encodeResponse(call, newObj.(runtime.Object), "updated", nil)
+			handler(un, recording.EventUpdated)
 		},
 		DeleteFunc: func(obj interface{}) {
 			un, ok := obj.(*unstructured.Unstructured)
@@ -150,7 +171,7 @@ func (h *WatchHandler) Handle(ctx context.Context, call rpc.Call, reply chan<- s
 				WithField("objectNamespace", un.GetNamespace()).
 				Trace("Informer event")
 
-			reply <- encodeResponse(call, obj.(runtime.Object), "deleted", nil)
+			handler(un, recording.EventDeleted)
 		},
 	})
 
@@ -158,11 +179,48 @@ func (h *WatchHandler) Handle(ctx context.Context, call rpc.Call, reply chan<- s
 	return nil
 }
 
+// record writes the event to the recorder (if recording is on).
+func record(
+	recorder *recording.Recorder,
+	kctx *kubeclient.Context,
+	params paramsWatch,
+	un *unstructured.Unstructured,
+	event recording.EventType,
+	logger *logrus.Entry,
+) {
+	if recorder == nil {
+		return
+	}
+
+	if err := recorder.Record(recording.Event{
+		Context:    kctx.Name(),
+		ClusterUID: kctx.ClusterUID(),
+		Group:      params.Group,
+		Version:    params.Version,
+		Resource:   params.Resource,
+		Type:       event,
+		Object:     un,
+	}); err != nil {
+		logger.
+			WithError(err).
+			Warn("couldn't record watch event")
+	}
+}
+
 func encodeResponse(call rpc.Call, obj runtime.Object, event string, err error) []byte {
+	return encodeTaggedResponse(call, obj, event, nil, err)
+}
+
+// encodeTaggedResponse is like encodeResponse, but the tags (e.g., the
+// context of the object) are added to the result (or to the error reply).
+func encodeTaggedResponse(call rpc.Call, obj runtime.Object, event string, tags map[string]interface{}, err error) []byte {
 	reply := map[string]interface{}{"id": call.ID}
 
 	if err != nil {
 		reply["error"] = err.Error()
+		for k, v := range tags {
+			reply[k] = v
+		}
 	} else {
 		var yaml bytes.Buffer
 		printr := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
@@ -176,11 +234,15 @@ func encodeResponse(call rpc.Call, obj runtime.Object, event string, err error)
 			reply["error"] = err.Error()
 		}
 
-		reply["result"] = map[string]string{
+		result := map[string]interface{}{
 			"yaml":  yaml.String(),
 			"json":  json.String(),
 			"event": event,
 		}
+		for k, v := range tags {
+			result[k] = v
+		}
+		reply["result"] = result
 	}
 
 	bytes, err := json.Marshal(reply)
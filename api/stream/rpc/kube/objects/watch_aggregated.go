@@ -0,0 +1,146 @@
+package objects
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/iximiuz/kexp/api/stream"
+	"github.com/iximiuz/kexp/api/stream/rpc"
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/logging"
+	"github.com/iximiuz/kexp/recording"
+)
+
+const WatchAggregated rpc.CallMethod = "kubeObjects.watchAggregated"
+
+type paramsWatchAggregated struct {
+	// Empty means all contexts of the pool.
+	Contexts      []string `json:"contexts"`
+	Group         string   `json:"group"`
+	Version       string   `json:"version"`
+	Resource      string   `json:"resource"`
+	Namespace     string   `json:"namespace"`
+	Name          string   `json:"name"`
+	FieldSelector string   `json:"fieldSelector"`
+	LabelSelector string   `json:"labelSelector"`
+}
+
+type WatchAggregatedHandler struct {
+	clientPool *kubeclient.ClientPool
+	recorder   *recording.Recorder
+	logger     *logrus.Entry
+}
+
+func NewWatchAggregatedHandler(clientPool *kubeclient.ClientPool, recorder *recording.Recorder) *WatchAggregatedHandler {
+	return &WatchAggregatedHandler{
+		clientPool: clientPool,
+		recorder:   recorder,
+		logger:     logrus.WithField("handler", "stream/rpc/kube/objects/watchAggregated"),
+	}
+}
+
+// Handle watches the same resource (and selection) in several contexts
+// at once. Replies are the same as the kubeObjects.watch ones, but the
+// result also has the context, the clusterUID, and the aliases - other
+// requested contexts pointing to the same cluster (they aren't watched
+// separately). Failures of individual contexts come as error replies
+// with the context set and don't stop the watch.
+func (h *WatchAggregatedHandler) Handle(ctx context.Context, call rpc.Call, reply chan<- stream.Message) error {
+	if call.Method != WatchAggregated {
+		return errors.New("call has been misdispatched")
+	}
+
+	logger := logging.WithRequestID(ctx, h.logger).
+		WithField("callId", call.ID).
+		WithField("callMethod", call.Method)
+
+	params := paramsWatchAggregated{}
+	if err := json.Unmarshal(call.Params, &params); err != nil {
+		logger.
+			WithError(err).
+			Warn("couldn't decode call params")
+		return err
+	}
+	if params.Group == "core" {
+		params.Group = ""
+	}
+
+	logger = logger.WithField("callParams", &params)
+	logger.Debug("Handling RPC call")
+
+	names := params.Contexts
+	if len(names) == 0 {
+		for _, kctx := range h.clientPool.Contexts() {
+			names = append(names, kctx.Name())
+		}
+		sort.Strings(names)
+	}
+
+	// The first context of a cluster is watched, the rest become its aliases.
+	var watched []*kubeclient.Context
+	aliases := map[string][]string{}
+	primaries := map[string]string{}
+
+	for _, name := range names {
+		kctx, err := h.clientPool.ContextFor(ctx, name)
+		if err != nil {
+			reply <- encodeTaggedResponse(call, nil, "", map[string]interface{}{"context": name}, err)
+			continue
+		}
+
+		uid := kctx.ClusterUID()
+		if primary, found := primaries[uid]; found && uid != "" {
+			aliases[primary] = append(aliases[primary], name)
+			continue
+		}
+		primaries[uid] = name
+		watched = append(watched, kctx)
+	}
+
+	var wg sync.WaitGroup
+	for _, kctx := range watched {
+		tags := map[string]interface{}{
+			"context":    kctx.Name(),
+			"clusterUID": kctx.ClusterUID(),
+			"aliases":    append([]string{}, aliases[kctx.Name()]...),
+		}
+
+		ctxParams := paramsWatch{
+			Context:       kctx.Name(),
+			Group:         params.Group,
+			Version:       params.Version,
+			Resource:      params.Resource,
+			Namespace:     params.Namespace,
+			Name:          params.Name,
+			FieldSelector: params.FieldSelector,
+			LabelSelector: params.LabelSelector,
+		}
+
+		ctxLogger := logger.WithField("context", kctx.Name())
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := inform(ctx, kctx, ctxParams, ctxLogger, func(un *unstructured.Unstructured, event recording.EventType) {
+				record(h.recorder, kctx, ctxParams, un, event, ctxLogger)
+				reply <- encodeTaggedResponse(call, un, string(event), tags, nil)
+			})
+			if err != nil {
+				ctxLogger.
+					WithError(err).
+					Warn("couldn't watch context")
+				reply <- encodeTaggedResponse(call, nil, "", tags, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,179 @@
+package objects
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/iximiuz/kexp/api/stream"
+	"github.com/iximiuz/kexp/api/stream/rpc"
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/logging"
+	"github.com/iximiuz/kexp/presets"
+	"github.com/iximiuz/kexp/relations"
+)
+
+const WatchRelated rpc.CallMethod = "kubeObjects.watchRelated"
+
+type paramsWatchRelated struct {
+	Context   string `json:"context"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Preset    string `json:"preset"`
+	Depth     int    `json:"depth"`
+}
+
+type WatchRelatedHandler struct {
+	clientPool *kubeclient.ClientPool
+	registry   *presets.Registry
+	logger     *logrus.Entry
+}
+
+func NewWatchRelatedHandler(
+	clientPool *kubeclient.ClientPool,
+	registry *presets.Registry,
+) *WatchRelatedHandler {
+	return &WatchRelatedHandler{
+		clientPool: clientPool,
+		registry:   registry,
+		logger:     logrus.WithField("handler", "stream/rpc/kube/objects/watchRelated"),
+	}
+}
+
+// Handle streams the changes of the live graph of objects related
+// to the target. Every reply carries a diff: nodes (with objects)
+// and edges added, updated, or removed since the previous reply.
+func (h *WatchRelatedHandler) Handle(ctx context.Context, call rpc.Call, reply chan<- stream.Message) error {
+	if call.Method != WatchRelated {
+		return errors.New("call has been misdispatched")
+	}
+
+	logger := logging.WithRequestID(ctx, h.logger).
+		WithField("callId", call.ID).
+		WithField("callMethod", call.Method)
+
+	params := paramsWatchRelated{}
+	if err := json.Unmarshal(call.Params, &params); err != nil {
+		logger.
+			WithError(err).
+			Warn("couldn't decode call params")
+		return err
+	}
+	if params.Group == "core" {
+		params.Group = ""
+	}
+	if params.Depth == 0 {
+		params.Depth = relations.DefaultDepth
+	}
+
+	logger = logger.WithField("callParams", &params)
+	logger.Debug("Handling RPC call")
+
+	if params.Depth < 0 || params.Depth > relations.MaxDepth {
+		reply <- encodeError(call, fmt.Errorf("invalid depth %d", params.Depth))
+		return nil
+	}
+
+	kctx, err := h.clientPool.ContextFor(ctx, params.Context)
+	if err != nil {
+		return err
+	}
+
+	preset, err := h.registry.Lookup(ctx, kctx, params.Preset)
+	if err != nil {
+		if errors.Is(err, presets.ErrNotFound) {
+			reply <- encodeError(call, err)
+			return nil
+		}
+		return err
+	}
+
+	discoveryClient, err := kctx.DiscoveryClient()
+	if err != nil {
+		return err
+	}
+
+	gvk, err := restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient).KindFor(schema.GroupVersionResource{
+		Group:    params.Group,
+		Version:  params.Version,
+		Resource: params.Resource,
+	})
+	if err != nil {
+		reply <- encodeError(call, err)
+		return nil
+	}
+
+	watcher := relations.NewWatcher(kctx, preset, relations.ObjectRef{
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Namespace: params.Namespace,
+		Name:      params.Name,
+	}, params.Depth, logger)
+
+	return watcher.Run(ctx, func(graph *relations.Graph, diff relations.GraphDiff) {
+		logger.
+			WithField("nodesAdded", len(diff.Nodes.Added)).
+			WithField("nodesUpdated", len(diff.Nodes.Updated)).
+			WithField("nodesRemoved", len(diff.Nodes.Removed)).
+			Trace("Related objects graph changed")
+
+		reply <- encodeGraphDiff(call, graph, diff)
+	})
+}
+
+type relatedNode struct {
+	relations.Node
+	Object *unstructured.Unstructured `json:"object,omitempty"`
+}
+
+func encodeGraphDiff(call rpc.Call, graph *relations.Graph, diff relations.GraphDiff) []byte {
+	withObjects := func(nodes []relations.Node) []relatedNode {
+		res := []relatedNode{}
+		for _, node := range nodes {
+			obj, _ := graph.Object(node.ID)
+			res = append(res, relatedNode{Node: node, Object: obj})
+		}
+		return res
+	}
+
+	withoutObjects := func(nodes []relations.Node) []relatedNode {
+		res := []relatedNode{}
+		for _, node := range nodes {
+			res = append(res, relatedNode{Node: node})
+		}
+		return res
+	}
+
+	bytes, err := json.Marshal(map[string]interface{}{
+		"id": call.ID,
+		"result": map[string]interface{}{
+			"event": "changed",
+			"root":  graph.Root,
+			"nodes": map[string]interface{}{
+				"added":   withObjects(diff.Nodes.Added),
+				"updated": withObjects(diff.Nodes.Updated),
+				"removed": withoutObjects(diff.Nodes.Removed),
+			},
+			"edges": diff.Edges,
+		},
+	})
+	if err != nil {
+		// Something really bad just happened.
+		panic(err.Error())
+	}
+	return bytes
+}
+
+func encodeError(call rpc.Call, err error) []byte {
+	return encodeResponse(call, nil, "", err)
+}
@@ -37,6 +37,8 @@ type CallDispatcher struct {
 	handlers    map[CallMethod]CallHandler
 	activeCalls map[CallID]context.CancelFunc
 	activeLock  sync.Mutex
+	running     sync.WaitGroup
+	closed      bool
 	logger      *logrus.Entry
 }
 
@@ -98,11 +100,21 @@ func (d *CallDispatcher) Handle(
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	d.activeLock.Lock()
+	if d.closed {
+		d.activeLock.Unlock()
+		logger.Debug("RPC call rejected - shutting down")
+		reply <- koReply(call, "Server is shutting down")
+		return nil
+	}
 	d.activeCalls[call.ID] = cancel
+	d.running.Add(1)
 	d.activeLock.Unlock()
 
+	defer d.running.Done()
+
 	err := handler.Handle(ctx, call, reply)
 
 	d.activeLock.Lock()
@@ -112,6 +124,31 @@ func (d *CallDispatcher) Handle(
 	return err
 }
 
+// Shutdown cancels all active calls (and rejects new ones) and waits
+// until the call handlers return - i.e., their informers are stopped.
+func (d *CallDispatcher) Shutdown(ctx context.Context) error {
+	d.activeLock.Lock()
+	d.closed = true
+	for id, cancel := range d.activeCalls {
+		cancel()
+		delete(d.activeCalls, id)
+	}
+	d.activeLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func okReply(call Call) stream.Message {
 	msg, err := json.Marshal(map[string]interface{}{"id": call.ID, "result": "ok"})
 	if err != nil {
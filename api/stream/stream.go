@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -15,6 +16,15 @@ import (
 
 type MessageType string
 
+const (
+	// Sent to every client before the server closes the
+	// connections (with the 1001 "going away" close code).
+	MessageTypeGoingAway MessageType = "goingAway"
+
+	// A client not reading its messages can't hold up the shutdown.
+	shutdownWriteTimeout = 1 * time.Second
+)
+
 type Message []byte
 
 type MessageHandler interface {
@@ -27,17 +37,20 @@ type Handler struct {
 
 	handlers map[MessageType]MessageHandler
 	upgrader websocket.Upgrader
+
+	mux          sync.Mutex
+	dispatchers  map[*messageDispatcher]struct{}
+	running      sync.WaitGroup
+	shuttingDown bool
 }
 
-func NewHandler(logger *logrus.Entry) *Handler {
+func NewHandler(checkOrigin func(r *http.Request) bool, logger *logrus.Entry) *Handler {
 	return &Handler{
-		Handler:  api.NewHandler("stream", logger),
-		handlers: make(map[MessageType]MessageHandler),
+		Handler:     api.NewHandler("stream", logger),
+		handlers:    make(map[MessageType]MessageHandler),
+		dispatchers: make(map[*messageDispatcher]struct{}),
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				// TODO: security
-				return true
-			},
+			CheckOrigin: checkOrigin,
 		},
 	}
 }
@@ -45,6 +58,16 @@ func NewHandler(logger *logrus.Entry) *Handler {
 func (h *Handler) Connect(c *gin.Context) {
 	logger := h.Logger(c).WithField("method", "connect")
 
+	h.mux.Lock()
+	if h.shuttingDown {
+		h.mux.Unlock()
+		c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "server is shutting down"})
+		return
+	}
+	h.running.Add(1)
+	h.mux.Unlock()
+	defer h.running.Done()
+
 	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		logger.WithError(err).Error("Couldn't upgrade to ws conn")
@@ -52,7 +75,91 @@ func (h *Handler) Connect(c *gin.Context) {
 		return
 	}
 
-	newMessageDispatcher(c.Request.Context(), conn, h.handlers, logger).runDispatchLoop()
+	d := newMessageDispatcher(c.Request.Context(), conn, h.handlers, logger)
+
+	h.mux.Lock()
+	h.dispatchers[d] = struct{}{}
+	h.mux.Unlock()
+
+	d.runDispatchLoop()
+
+	h.mux.Lock()
+	delete(h.dispatchers, d)
+	h.mux.Unlock()
+}
+
+// GoAway tells the connected clients that the server is about
+// to shut down and stops accepting new connections.
+func (h *Handler) GoAway() {
+	h.mux.Lock()
+	if h.shuttingDown {
+		h.mux.Unlock()
+		return
+	}
+	h.shuttingDown = true
+	h.mux.Unlock()
+
+	msg, err := json.Marshal(map[string]interface{}{
+		"type":   MessageTypeGoingAway,
+		"reason": "server is shutting down",
+	})
+	if err != nil {
+		// Something really bad just happened.
+		panic(err)
+	}
+
+	// Not under the lock - finished connections must be able
+	// to unregister while the slow ones are being written to.
+	var wg sync.WaitGroup
+	for _, d := range h.connected() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.writeMessageWithDeadline(msg, time.Now().Add(shutdownWriteTimeout))
+		}()
+	}
+	wg.Wait()
+}
+
+// Shutdown closes the connections with the "going away" close code
+// and waits until their dispatch loops are over. The message handlers
+// are expected to be shut down (or at least notified) before the call.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.GoAway()
+
+	for _, d := range h.connected() {
+		d.closeGoingAway()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		// Clients that didn't reply to the close frame in time.
+		for _, d := range h.connected() {
+			d.cancel()
+		}
+		return ctx.Err()
+	}
+}
+
+// connected returns a copy of the current dispatchers.
+func (h *Handler) connected() []*messageDispatcher {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	ds := make([]*messageDispatcher, 0, len(h.dispatchers))
+	for d := range h.dispatchers {
+		ds = append(ds, d)
+	}
+	return ds
 }
 
 func (h *Handler) RegisterMessageHandler(msgType MessageType, handler MessageHandler) {
@@ -165,6 +272,11 @@ func (d *messageDispatcher) readMessage() (Message, error) {
 
 	go func() {
 		msgType, data, err := d.conn.ReadMessage()
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			d.logger.Info("ws connection has been closed by peer")
+			read <- result{data: nil, err: nil}
+			return
+		}
 		if err != nil {
 			d.logger.WithError(err).Error("Couldn't read ws message")
 			read <- result{data: nil, err: err}
@@ -189,9 +301,20 @@ func (d *messageDispatcher) readMessage() (Message, error) {
 }
 
 func (d *messageDispatcher) writeMessage(msg Message) {
+	d.writeMessageWithDeadline(msg, time.Time{})
+}
+
+// writeMessageWithDeadline is like writeMessage, but the write fails
+// if it isn't done by the deadline (zero means no deadline).
+func (d *messageDispatcher) writeMessageWithDeadline(msg Message, deadline time.Time) {
 	d.msgWriteLock.Lock()
 	defer d.msgWriteLock.Unlock()
 
+	if !deadline.IsZero() {
+		_ = d.conn.SetWriteDeadline(deadline)
+		defer d.conn.SetWriteDeadline(time.Time{})
+	}
+
 	if err := d.conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
 		d.logger.
 			WithError(err).
@@ -202,3 +325,22 @@ func (d *messageDispatcher) writeMessage(msg Message) {
 		d.cancel()
 	}
 }
+
+// closeGoingAway starts the closing handshake - the dispatch
+// loop is over when the peer replies with its close frame.
+func (d *messageDispatcher) closeGoingAway() {
+	d.msgWriteLock.Lock()
+	defer d.msgWriteLock.Unlock()
+
+	err := d.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down"),
+		time.Now().Add(shutdownWriteTimeout),
+	)
+	if err != nil {
+		d.logger.WithError(err).Warn("couldn't write ws close message")
+
+		// No point in waiting for the peer's reply.
+		d.cancel()
+	}
+}
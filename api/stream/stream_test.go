@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+func TestShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	h := NewHandler(func(*http.Request) bool { return true }, logrus.NewEntry(logger))
+
+	router := gin.New()
+	router.GET("/api/stream/v1/", h.Connect)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/stream/v1/"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("couldn't connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Make sure the connection is registered before going away.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(h.connected()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("connection wasn't registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h.GoAway()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("waiting for goingAway: %v", err)
+	}
+	var msg struct {
+		Type MessageType `json:"type"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != MessageTypeGoingAway {
+		t.Fatalf("got %s, want a goingAway message", data)
+	}
+
+	// No new connections while shutting down.
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("connecting while shutting down: err = %v, want 503", err)
+	}
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- h.Shutdown(ctx)
+	}()
+
+	// The client replies to the close frame (gorilla does it while reading).
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Errorf("got %v, want close %d", err, websocket.CloseGoingAway)
+	}
+
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Shutdown() failed: %v", err)
+	}
+}
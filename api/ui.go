@@ -0,0 +1,54 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const uiIndex = "index.html"
+
+// UIHandler serves the UI's static files. The UI is built with relative
+// asset URLs, and the index.html gets the base path injected, so the same
+// build works under any --base-path and behind path-rewriting proxies.
+type UIHandler struct {
+	fsys fs.FS
+}
+
+func NewUIHandler(fsys fs.FS) *UIHandler {
+	return &UIHandler{fsys: fsys}
+}
+
+// GET ui/*filepath
+func (h *UIHandler) Serve(c *gin.Context) {
+	path := c.Param("filepath")
+	if path != "/" && path != "/"+uiIndex {
+		c.FileFromFS(path, http.FS(h.fsys))
+		return
+	}
+
+	index, err := fs.ReadFile(h.fsys, uiIndex)
+	if err != nil {
+		c.AbortWithStatusJSON(
+			http.StatusNotFound,
+			map[string]string{"error": "UI is not built"},
+		)
+		return
+	}
+
+	// json.Marshal escapes <, >, and & - safe to put into a <script>.
+	basePath, _ := json.Marshal(BasePath(c))
+	inject := []byte(`<script>window.KEXP_BASE_PATH = ` + string(basePath) + `;</script>`)
+
+	if i := bytes.Index(index, []byte("</head>")); i != -1 {
+		index = append(index[:i:i], append(inject, index[i:]...)...)
+	} else {
+		index = append(inject, index...)
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+}
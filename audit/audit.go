@@ -0,0 +1,243 @@
+// Package audit keeps track of the changes made to the clusters
+// through kexp: who changed (or deleted) what, when, and how.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/iximiuz/kexp/diff"
+)
+
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+
+	// Max number of (most recent) entries available for queries.
+	maxEntries = 10000
+
+	redacted = "<redacted>"
+)
+
+// Entry is a line of the audit log.
+type Entry struct {
+	Time time.Time `json:"time"`
+
+	RequestID string `json:"requestID"`
+	Principal string `json:"principal,omitempty"`
+
+	// The identity the action was performed as in the cluster
+	// (if kexp impersonated someone).
+	ImpersonatedUser   string   `json:"impersonatedUser,omitempty"`
+	ImpersonatedGroups []string `json:"impersonatedGroups,omitempty"`
+
+	// The client's address (X-Forwarded-For only from a trusted proxy)
+	// and the address of the connection (e.g., the proxy's) as is.
+	ClientAddr string `json:"clientAddr"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+
+	Verb string `json:"verb"`
+
+	Context   string `json:"context"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+
+	// Before -> after (for deletions, the after is empty).
+	Changes []diff.Change `json:"changes,omitempty"`
+}
+
+// Log appends the entries to a JSONL file (if any) and keeps
+// the most recent ones in memory to serve the queries.
+type Log struct {
+	mux sync.RWMutex
+
+	file    *os.File
+	encoder *json.Encoder
+
+	entries []Entry
+}
+
+// Open starts (or continues) the log in the file.
+// With no path, the entries are kept in memory only.
+func Open(path string) (*Log, error) {
+	l := &Log{}
+	if path == "" {
+		return l, nil
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		for i, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			entry := Entry{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				return nil, fmt.Errorf("audit log %s line %d: %w", path, i+1, err)
+			}
+			l.keep(entry)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("couldn't read audit log: %w", err)
+	}
+
+	// The log may contain bits of the objects - keep it private.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open audit log: %w", err)
+	}
+
+	l.file = file
+	l.encoder = json.NewEncoder(file)
+	return l, nil
+}
+
+func (l *Log) Append(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.keep(entry)
+
+	if l.encoder == nil {
+		return nil
+	}
+	return l.encoder.Encode(entry)
+}
+
+func (l *Log) keep(entry Entry) {
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > maxEntries {
+		l.entries = l.entries[len(l.entries)-maxEntries:]
+	}
+}
+
+// Query filters the entries. Empty fields match anything.
+type Query struct {
+	Principal string
+	Verb      string
+	Context   string
+	Resource  string
+	Namespace string
+	Name      string
+	Since     time.Time
+
+	// Zero means no limit.
+	Limit int
+}
+
+// Find returns the matching entries, the most recent first.
+func (l *Log) Find(q Query) []Entry {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+
+	found := []Entry{}
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if q.Limit > 0 && len(found) >= q.Limit {
+			break
+		}
+
+		e := l.entries[i]
+		if !q.Since.IsZero() && e.Time.Before(q.Since) {
+			break // The entries are ordered by time.
+		}
+
+		if matches(q.Principal, e.Principal) &&
+			matches(q.Verb, e.Verb) &&
+			matches(q.Context, e.Context) &&
+			matches(q.Resource, e.Resource) &&
+			matches(q.Namespace, e.Namespace) &&
+			matches(q.Name, e.Name) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func matches(want, got string) bool {
+	return want == "" || want == got
+}
+
+func (l *Log) Close() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Changes compares the object before and after the action (nil means
+// the object didn't exist). The noisy fields are ignored (see
+// diff.Normalize) and Secrets' values are redacted.
+func Changes(before, after *unstructured.Unstructured) []diff.Change {
+	if before == nil && after == nil {
+		return nil
+	}
+
+	secret := false
+	normalize := func(obj *unstructured.Unstructured) *unstructured.Unstructured {
+		if obj == nil {
+			return &unstructured.Unstructured{Object: map[string]interface{}{}}
+		}
+		if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Secret" {
+			secret = true
+		}
+		return diff.Normalize(obj)
+	}
+
+	changes := diff.Compare(normalize(before), normalize(after))
+	if !secret {
+		return changes
+	}
+
+	for i, change := range changes {
+		if !isSecretData(change.Path) {
+			continue
+		}
+		if change.Left != nil {
+			changes[i].Left = redactValues(change.Left)
+		}
+		if change.Right != nil {
+			changes[i].Right = redactValues(change.Right)
+		}
+	}
+	return changes
+}
+
+func isSecretData(path string) bool {
+	for _, field := range []string{"data", "stringData"} {
+		if path == field || strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValues keeps the keys (to show what's changed) but not the values.
+func redactValues(val interface{}) interface{} {
+	if m, ok := val.(map[string]interface{}); ok {
+		res := map[string]interface{}{}
+		for key := range m {
+			res[key] = redacted
+		}
+		return res
+	}
+	return redacted
+}
@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/iximiuz/kexp/diff"
+)
+
+func TestChanges(t *testing.T) {
+	configMap := func(level string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "web", "resourceVersion": level},
+			"data":       map[string]interface{}{"LOG_LEVEL": level},
+		}}
+	}
+	secret := func(password string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "db"},
+			"data":       map[string]interface{}{"password": password},
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		before *unstructured.Unstructured
+		after  *unstructured.Unstructured
+		want   []diff.Change
+	}{
+		{
+			name:   "update",
+			before: configMap("info"),
+			after:  configMap("debug"),
+			want:   []diff.Change{{Path: "data.LOG_LEVEL", Type: diff.ChangeChanged, Left: "info", Right: "debug"}},
+		},
+		{
+			name:   "no changes",
+			before: configMap("info"),
+			after:  configMap("info"),
+			want:   []diff.Change{},
+		},
+		{
+			name:   "secret update",
+			before: secret("c2VjcmV0"),
+			after:  secret("aHVudGVyMg=="),
+			want:   []diff.Change{{Path: "data.password", Type: diff.ChangeChanged, Left: redacted, Right: redacted}},
+		},
+		{
+			name:   "secret deletion",
+			before: secret("c2VjcmV0"),
+			after:  nil,
+			want: []diff.Change{
+				{Path: "apiVersion", Type: diff.ChangeRemoved, Left: "v1"},
+				{Path: "data", Type: diff.ChangeRemoved, Left: map[string]interface{}{"password": redacted}},
+				{Path: "kind", Type: diff.ChangeRemoved, Left: "Secret"},
+				{Path: "metadata", Type: diff.ChangeRemoved, Left: map[string]interface{}{"name": "db"}},
+			},
+		},
+		{
+			name: "nothing",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Changes(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	l, err := Open("")
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+
+	start := time.Now().UTC().Add(-time.Hour)
+	for i, e := range []Entry{
+		{Principal: "alice", Verb: "update", Context: "prod", Resource: "deployments", Namespace: "default", Name: "web"},
+		{Principal: "bob", Verb: "delete", Context: "prod", Resource: "pods", Namespace: "default", Name: "web-0"},
+		{Principal: "alice", Verb: "delete", Context: "dev", Resource: "pods", Namespace: "kube-system", Name: "dns-0"},
+	} {
+		e.Time = start.Add(time.Duration(i) * time.Minute)
+		if err := l.Append(e); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     Query
+		wantNames []string
+	}{
+		{"everything, most recent first", Query{}, []string{"dns-0", "web-0", "web"}},
+		{"by principal", Query{Principal: "alice"}, []string{"dns-0", "web"}},
+		{"by verb and context", Query{Verb: "delete", Context: "prod"}, []string{"web-0"}},
+		{"by namespace", Query{Namespace: "kube-system"}, []string{"dns-0"}},
+		{"since", Query{Since: start.Add(30 * time.Second)}, []string{"dns-0", "web-0"}},
+		{"limit", Query{Limit: 1}, []string{"dns-0"}},
+		{"no match", Query{Resource: "secrets"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, e := range l.Find(tt.query) {
+				names = append(names, e.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("Find() = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestOpenContinuesLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	entry := Entry{
+		Principal:          "alice",
+		ImpersonatedUser:   "bob",
+		ImpersonatedGroups: []string{"devs"},
+		ClientAddr:         "10.0.0.7",
+		RemoteAddr:         "10.0.0.1:51234",
+		Verb:               "delete",
+		Name:               "web",
+		Outcome:            OutcomeSucceeded,
+	}
+	if err := l.Append(entry); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("audit log permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer l.Close()
+
+	entries := l.Find(Query{})
+	if len(entries) != 1 {
+		t.Fatalf("Find() = %+v, want 1 entry", entries)
+	}
+	got := entries[0]
+	if got.ImpersonatedUser != "bob" || len(got.ImpersonatedGroups) != 1 || got.RemoteAddr != entry.RemoteAddr || got.ClientAddr != entry.ClientAddr {
+		t.Errorf("Find() = %+v, want %+v", got, entry)
+	}
+}
@@ -0,0 +1,131 @@
+// Package certs provides the server certificates for serving
+// the UI and the API over HTTPS (and the stream over wss://).
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const selfSignedTTL = 365 * 24 * time.Hour
+
+// Load reads a PEM-encoded certificate (chain) and its private key.
+func Load(certFile, keyFile string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("couldn't load TLS certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// SelfSigned generates a certificate for the host names and IP addresses.
+// It lives in memory only, so every start gets a new one (and a new
+// fingerprint that the users can verify in the browser).
+func SelfSigned(hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("couldn't generate private key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("couldn't generate serial number: %w", err)
+	}
+
+	// A leaf explicitly marked as not a CA - trusting it in the browser
+	// must not let it vouch for any other certificate.
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"kexp"}, CommonName: "kexp self-signed"},
+		NotBefore:             now.Add(-time.Hour), // Some tolerance for clock skew.
+		NotAfter:              now.Add(selfSignedTTL),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if host != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("couldn't create certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// Hosts returns the names and addresses the server is likely
+// to be reached by when listening on the host.
+func Hosts(listenHost string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+
+	ip := net.ParseIP(listenHost)
+	if listenHost != "" && (ip == nil || !ip.IsUnspecified()) {
+		return appendUnique(hosts, listenHost)
+	}
+
+	// 0.0.0.0, ::, or empty - any of the machine's names and addresses.
+	if name, err := os.Hostname(); err == nil {
+		hosts = appendUnique(hosts, name)
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+				hosts = appendUnique(hosts, ipNet.IP.String())
+			}
+		}
+	}
+	return hosts
+}
+
+// Fingerprint is the SHA-256 hash of the (leaf) certificate
+// in the format browsers and openssl show it.
+func Fingerprint(cert tls.Certificate) string {
+	if len(cert.Certificate) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(cert.Certificate[0])
+
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+func appendUnique(hosts []string, host string) []string {
+	for _, h := range hosts {
+		if h == host {
+			return hosts
+		}
+	}
+	return append(hosts, host)
+}
@@ -0,0 +1,32 @@
+package certs
+
+import (
+	"crypto/x509"
+	"net"
+	"testing"
+)
+
+func TestSelfSigned(t *testing.T) {
+	cert, err := SelfSigned([]string{"localhost", "127.0.0.1", "kexp.example.com", ""})
+	if err != nil {
+		t.Fatalf("SelfSigned() failed: %v", err)
+	}
+	leaf := cert.Leaf
+
+	if leaf.IsCA || !leaf.BasicConstraintsValid {
+		t.Error("the certificate is (or may be used as) a CA")
+	}
+	if leaf.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Errorf("key usage = %v, want digital signature only", leaf.KeyUsage)
+	}
+
+	if err := leaf.VerifyHostname("kexp.example.com"); err != nil {
+		t.Errorf("VerifyHostname() failed: %v", err)
+	}
+	if len(leaf.IPAddresses) != 1 || !leaf.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("IP addresses = %v, want 127.0.0.1", leaf.IPAddresses)
+	}
+	if len(leaf.DNSNames) != 2 {
+		t.Errorf("DNS names = %v, want localhost and kexp.example.com", leaf.DNSNames)
+	}
+}
@@ -0,0 +1,271 @@
+// Package diff compares Kubernetes objects field by field.
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// Change is a difference at a path (e.g., spec.template.spec.containers[0].image).
+// Added means the field is only in the right object, removed - only in the left one.
+type Change struct {
+	Path  string      `json:"path"`
+	Type  ChangeType  `json:"type"`
+	Left  interface{} `json:"left,omitempty"`
+	Right interface{} `json:"right,omitempty"`
+}
+
+// Fields that differ between any two copies of the same object.
+var noisyMetadataFields = []string{
+	"managedFields",
+	"resourceVersion",
+	"uid",
+	"generation",
+	"creationTimestamp",
+	"selfLink",
+}
+
+var noisyAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+}
+
+// Normalize returns a copy of the object without the fields that
+// are unique to a particular copy of it (UIDs, resource versions,
+// managed fields, status timestamps, etc.), so two copies deployed
+// to different clusters (or namespaces) can be compared meaningfully.
+func Normalize(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+
+	for _, field := range noisyMetadataFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "namespace")
+
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		for _, key := range noisyAnnotations {
+			delete(annotations, key)
+		}
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		obj.SetAnnotations(annotations)
+	}
+
+	if refs, found, _ := unstructured.NestedSlice(obj.Object, "metadata", "ownerReferences"); found {
+		for _, ref := range refs {
+			if ref, ok := ref.(map[string]interface{}); ok {
+				delete(ref, "uid")
+			}
+		}
+		_ = unstructured.SetNestedSlice(obj.Object, refs, "metadata", "ownerReferences")
+	}
+
+	if status, ok := obj.Object["status"]; ok {
+		obj.Object["status"] = withoutTimestamps(status)
+	}
+
+	return obj
+}
+
+// isTimestampField matches lastTransitionTime, startTime, startedAt, etc.
+func isTimestampField(name string) bool {
+	return strings.HasSuffix(name, "Time") ||
+		strings.HasSuffix(name, "Timestamp") ||
+		strings.HasSuffix(name, "At")
+}
+
+func withoutTimestamps(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		res := map[string]interface{}{}
+		for key, item := range v {
+			if isTimestampField(key) {
+				continue
+			}
+			res[key] = withoutTimestamps(item)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			res = append(res, withoutTimestamps(item))
+		}
+		return res
+	}
+	return val
+}
+
+// Compare returns the differences between the two objects (as is -
+// normalize them first if needed). The changes are sorted by path.
+func Compare(left, right *unstructured.Unstructured) []Change {
+	changes := []Change{}
+	compare("", left.Object, right.Object, &changes)
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func compare(path string, left, right interface{}, changes *[]Change) {
+	switch l := left.(type) {
+	case map[string]interface{}:
+		if r, ok := right.(map[string]interface{}); ok {
+			for key, lval := range l {
+				if rval, found := r[key]; found {
+					compare(join(path, key), lval, rval, changes)
+				} else {
+					*changes = append(*changes, Change{Path: join(path, key), Type: ChangeRemoved, Left: lval})
+				}
+			}
+			for key, rval := range r {
+				if _, found := l[key]; !found {
+					*changes = append(*changes, Change{Path: join(path, key), Type: ChangeAdded, Right: rval})
+				}
+			}
+			return
+		}
+
+	case []interface{}:
+		if r, ok := right.([]interface{}); ok {
+			for i := 0; i < len(l) || i < len(r); i++ {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(r):
+					*changes = append(*changes, Change{Path: itemPath, Type: ChangeRemoved, Left: l[i]})
+				case i >= len(l):
+					*changes = append(*changes, Change{Path: itemPath, Type: ChangeAdded, Right: r[i]})
+				default:
+					compare(itemPath, l[i], r[i], changes)
+				}
+			}
+			return
+		}
+	}
+
+	// Integers may come as int64 or float64 depending on the decoder.
+	if l, ok := asFloat(left); ok {
+		if r, ok := asFloat(right); ok && l == r {
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(left, right) {
+		*changes = append(*changes, Change{Path: path, Type: ChangeChanged, Left: left, Right: right})
+	}
+}
+
+func asFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func join(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+type Status string
+
+const (
+	StatusIdentical Status = "identical"
+	StatusDifferent Status = "different"
+	StatusLeftOnly  Status = "leftOnly"
+	StatusRightOnly Status = "rightOnly"
+)
+
+type ObjectDiff struct {
+	Kind      string   `json:"kind"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+	Status    Status   `json:"status"`
+	Changes   []Change `json:"changes,omitempty"`
+}
+
+// Objects pairs the objects by kind and name and compares the
+// normalized pairs. With matchNamespaces, the objects are paired only
+// within the same namespace - set it unless the sides are two different
+// namespaces (otherwise, e.g., default/web and prod/web of an all-namespaces
+// listing would collide). The result is sorted by kind, namespace, and name.
+func Objects(left, right []*unstructured.Unstructured, matchNamespaces bool) []ObjectDiff {
+	key := func(obj *unstructured.Unstructured) string {
+		if matchNamespaces {
+			return obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+		}
+		return obj.GetKind() + "/" + obj.GetName()
+	}
+	objectDiff := func(obj *unstructured.Unstructured, status Status) ObjectDiff {
+		od := ObjectDiff{Kind: obj.GetKind(), Name: obj.GetName(), Status: status}
+		if matchNamespaces {
+			od.Namespace = obj.GetNamespace()
+		}
+		return od
+	}
+
+	rights := map[string]*unstructured.Unstructured{}
+	for _, obj := range right {
+		rights[key(obj)] = obj
+	}
+
+	res := []ObjectDiff{}
+	seen := map[string]bool{}
+
+	for _, l := range left {
+		seen[key(l)] = true
+
+		r, found := rights[key(l)]
+		if !found {
+			res = append(res, objectDiff(l, StatusLeftOnly))
+			continue
+		}
+
+		od := objectDiff(l, StatusIdentical)
+		if changes := Compare(Normalize(l), Normalize(r)); len(changes) > 0 {
+			od.Status = StatusDifferent
+			od.Changes = changes
+		}
+		res = append(res, od)
+	}
+
+	for _, r := range right {
+		if !seen[key(r)] {
+			res = append(res, objectDiff(r, StatusRightOnly))
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Kind != res[j].Kind {
+			return res[i].Kind < res[j].Kind
+		}
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
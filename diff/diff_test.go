@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  map[string]interface{}
+		right map[string]interface{}
+		want  []Change
+	}{
+		{
+			name:  "identical",
+			left:  map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			right: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			want:  []Change{},
+		},
+		{
+			name:  "int64 vs float64",
+			left:  map[string]interface{}{"replicas": int64(3)},
+			right: map[string]interface{}{"replicas": float64(3)},
+			want:  []Change{},
+		},
+		{
+			name:  "changed, added, and removed",
+			left:  map[string]interface{}{"image": "web:1", "debug": true},
+			right: map[string]interface{}{"image": "web:2", "replicas": int64(2)},
+			want: []Change{
+				{Path: "debug", Type: ChangeRemoved, Left: true},
+				{Path: "image", Type: ChangeChanged, Left: "web:1", Right: "web:2"},
+				{Path: "replicas", Type: ChangeAdded, Right: int64(2)},
+			},
+		},
+		{
+			name:  "list items",
+			left:  map[string]interface{}{"args": []interface{}{"a", "b"}},
+			right: map[string]interface{}{"args": []interface{}{"a", "c", "d"}},
+			want: []Change{
+				{Path: "args[1]", Type: ChangeChanged, Left: "b", Right: "c"},
+				{Path: "args[2]", Type: ChangeAdded, Right: "d"},
+			},
+		},
+		{
+			name:  "keys with dots",
+			left:  map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "web"}},
+			right: map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "api"}},
+			want:  []Change{{Path: `labels["app.kubernetes.io/name"]`, Type: ChangeChanged, Left: "web", Right: "api"}},
+		},
+		{
+			name:  "type change",
+			left:  map[string]interface{}{"port": "http"},
+			right: map[string]interface{}{"port": int64(80)},
+			want:  []Change{{Path: "port", Type: ChangeChanged, Left: "http", Right: int64(80)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(&unstructured.Unstructured{Object: tt.left}, &unstructured.Unstructured{Object: tt.right})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjects(t *testing.T) {
+	deployment := func(namespace, name, image string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"namespace":       namespace,
+				"name":            name,
+				"uid":             namespace + "-" + name,
+				"resourceVersion": "1",
+			},
+			"spec": map[string]interface{}{"image": image},
+		}}
+	}
+
+	tests := []struct {
+		name            string
+		left            []*unstructured.Unstructured
+		right           []*unstructured.Unstructured
+		matchNamespaces bool
+		want            []ObjectDiff
+	}{
+		{
+			name:            "same namespace",
+			left:            []*unstructured.Unstructured{deployment("default", "web", "web:1"), deployment("default", "api", "api:1")},
+			right:           []*unstructured.Unstructured{deployment("default", "web", "web:2"), deployment("default", "db", "db:1")},
+			matchNamespaces: true,
+			want: []ObjectDiff{
+				{Kind: "Deployment", Namespace: "default", Name: "api", Status: StatusLeftOnly},
+				{Kind: "Deployment", Namespace: "default", Name: "db", Status: StatusRightOnly},
+				{Kind: "Deployment", Namespace: "default", Name: "web", Status: StatusDifferent, Changes: []Change{
+					{Path: "spec.image", Type: ChangeChanged, Left: "web:1", Right: "web:2"},
+				}},
+			},
+		},
+		{
+			name:            "all namespaces",
+			left:            []*unstructured.Unstructured{deployment("dev", "web", "web:1"), deployment("prod", "web", "web:1")},
+			right:           []*unstructured.Unstructured{deployment("dev", "web", "web:1"), deployment("staging", "web", "web:1")},
+			matchNamespaces: true,
+			want: []ObjectDiff{
+				{Kind: "Deployment", Namespace: "dev", Name: "web", Status: StatusIdentical},
+				{Kind: "Deployment", Namespace: "prod", Name: "web", Status: StatusLeftOnly},
+				{Kind: "Deployment", Namespace: "staging", Name: "web", Status: StatusRightOnly},
+			},
+		},
+		{
+			name:  "two namespaces",
+			left:  []*unstructured.Unstructured{deployment("staging", "web", "web:2")},
+			right: []*unstructured.Unstructured{deployment("prod", "web", "web:1")},
+			want: []ObjectDiff{
+				{Kind: "Deployment", Name: "web", Status: StatusDifferent, Changes: []Change{
+					{Path: "spec.image", Type: ChangeChanged, Left: "web:2", Right: "web:1"},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Objects(tt.left, tt.right, tt.matchNamespaces); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Objects() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
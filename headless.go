@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/iximiuz/kexp/api/stream"
+	streamrpc "github.com/iximiuz/kexp/api/stream/rpc"
+	streamkubeobjects "github.com/iximiuz/kexp/api/stream/rpc/kube/objects"
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/presets"
+	"github.com/iximiuz/kexp/relations"
+)
+
+// Headless commands reuse the daemon's logic but print the results
+// to stdout instead of serving them. The --context, --namespace,
+// --snapshot, --fake-cluster, and --presets-dir flags work the same way.
+
+type headlessFlags struct {
+	output        string
+	depth         int
+	preset        string
+	allNamespaces bool
+	labelSelector string
+	fieldSelector string
+}
+
+func addHeadlessCommands(root *cobra.Command, flags *flagpole) {
+	graphFlags := &headlessFlags{}
+	graphCmd := &cobra.Command{
+		Use:   "graph <kind/name>",
+		Short: "Print the graph of objects related to the given one",
+		Example: "  kexp graph deployment/web\n" +
+			"  kexp graph -n kube-system pod/coredns-7db6d8ff4d-2xqgd --depth 1 -o mermaid",
+		Args: cobra.ExactArgs(1),
+		RunE: headless(flags, func(ctx context.Context, pool *kubeclient.ClientPool, args []string) error {
+			return graph(ctx, flags, graphFlags, pool, args[0], os.Stdout)
+		}),
+	}
+	graphCmd.Flags().StringVarP(&graphFlags.output, "output", "o", string(relations.FormatTree), "Output format: tree, dot, mermaid, graphml, or json")
+	graphCmd.Flags().IntVar(&graphFlags.depth, "depth", relations.DefaultDepth, "How many hops to follow from the object")
+	graphCmd.Flags().StringVar(&graphFlags.preset, "preset", "", "Graph preset (default: related)")
+
+	exportFlags := &headlessFlags{}
+	exportCmd := &cobra.Command{
+		Use:   "export <resource>",
+		Short: "Export the objects of a resource and the relations between them",
+		Example: "  kexp export pods -l app=web -o mermaid\n" +
+			"  kexp export deployments.apps --all-namespaces > deployments.dot",
+		Args: cobra.ExactArgs(1),
+		RunE: headless(flags, func(ctx context.Context, pool *kubeclient.ClientPool, args []string) error {
+			return export(ctx, flags, exportFlags, pool, args[0], os.Stdout)
+		}),
+	}
+	exportCmd.Flags().StringVarP(&exportFlags.output, "output", "o", string(relations.FormatDOT), "Output format: tree, dot, mermaid, graphml, or json")
+	exportCmd.Flags().StringVar(&exportFlags.preset, "preset", "", "Graph preset (default: related)")
+	exportCmd.Flags().BoolVarP(&exportFlags.allNamespaces, "all-namespaces", "A", false, "Export objects from all namespaces")
+	exportCmd.Flags().StringVarP(&exportFlags.labelSelector, "selector", "l", "", "Label selector")
+	exportCmd.Flags().StringVar(&exportFlags.fieldSelector, "field-selector", "", "Field selector")
+
+	watchFlags := &headlessFlags{}
+	watchCmd := &cobra.Command{
+		Use:   "watch <resource>[/<name>]",
+		Short: "Stream watch events as JSON lines (same envelope as the WebSocket API)",
+		Example: "  kexp watch pods -l app=web\n" +
+			"  kexp watch deployment/web | jq -r .result.event",
+		Args: cobra.ExactArgs(1),
+		RunE: headless(flags, func(ctx context.Context, pool *kubeclient.ClientPool, args []string) error {
+			return watch(ctx, flags, watchFlags, pool, args[0])
+		}),
+	}
+	watchCmd.Flags().BoolVarP(&watchFlags.allNamespaces, "all-namespaces", "A", false, "Watch objects in all namespaces")
+	watchCmd.Flags().StringVarP(&watchFlags.labelSelector, "selector", "l", "", "Label selector")
+	watchCmd.Flags().StringVar(&watchFlags.fieldSelector, "field-selector", "", "Field selector")
+
+	root.AddCommand(graphCmd, exportCmd, watchCmd)
+}
+
+// headless sets up the logging (stdout is for the results) and the
+// client pool, and runs the command until it's done or interrupted.
+func headless(
+	flags *flagpole,
+	fn func(ctx context.Context, pool *kubeclient.ClientPool, args []string) error,
+) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true // Reported by main().
+
+		logrus.SetFormatter(&logrus.TextFormatter{})
+		logrus.SetOutput(os.Stderr)
+		logrus.SetLevel(logrus.WarnLevel)
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		var (
+			pool *kubeclient.ClientPool
+			err  error
+		)
+		if len(flags.snapshots) > 0 || flags.fakeClusterDir != "" {
+			pool, err = initOfflineClientPool(flags.snapshots, flags.fakeClusterDir)
+			if err == nil && *flags.Context != "" {
+				err = pool.SetCurrent(*flags.Context)
+			}
+		} else {
+			pool, err = initCurrentContextClientPool(ctx, flags)
+		}
+		if err != nil {
+			return err
+		}
+
+		return fn(ctx, pool, args)
+	}
+}
+
+// initCurrentContextClientPool is a faster alternative to initKubeClientPool
+// for one-off commands - only the current (or --context) context is added.
+func initCurrentContextClientPool(ctx context.Context, flags *flagpole) (*kubeclient.ClientPool, error) {
+	rateLimits, err := parseRateLimits(flags.rateLimits, flags.burst)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := kubeclient.NewPool()
+	pool.SetRateLimits(kubeclient.RateLimit{QPS: flags.qps, Burst: flags.burst}, rateLimits)
+
+	if flags.inCluster && (*flags.Context == "" || *flags.Context == kubeclient.InClusterContext) {
+		if err := pool.AddInCluster(ctx); err != nil {
+			return nil, err
+		}
+		return pool, nil
+	}
+
+	rawConfig, err := flags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	name := *flags.Context
+	if name == "" {
+		name = rawConfig.CurrentContext
+	}
+
+	kctx, found := rawConfig.Contexts[name]
+	if !found {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", name)
+	}
+
+	config, err := flags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Add(ctx, name, kctx.AuthInfo, kctx.Cluster, kctx.Namespace, config); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func graph(
+	ctx context.Context,
+	flags *flagpole,
+	hflags *headlessFlags,
+	pool *kubeclient.ClientPool,
+	arg string,
+	out io.Writer,
+) error {
+	resource, name, found := strings.Cut(arg, "/")
+	if !found || name == "" {
+		return errors.New("expected <kind/name> argument (e.g., deployment/web)")
+	}
+
+	if hflags.depth < 0 || hflags.depth > relations.MaxDepth {
+		return fmt.Errorf("depth must be between 0 and %d", relations.MaxDepth)
+	}
+
+	kctx := pool.CurrentContext()
+
+	src, preset, err := prepareGraph(ctx, flags, hflags, kctx)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := resolveResource(kctx, src, resource)
+	if err != nil {
+		return err
+	}
+
+	graph, err := preset.Engine(src).Related(ctx, relations.ObjectRef{
+		Group:     mapping.GroupVersionKind.Group,
+		Version:   mapping.GroupVersionKind.Version,
+		Kind:      mapping.GroupVersionKind.Kind,
+		Namespace: namespaceFor(flags, hflags, kctx, mapping),
+		Name:      name,
+	}, hflags.depth)
+	if err != nil {
+		if errors.Is(err, relations.ErrNotFound) {
+			return fmt.Errorf("%s not found", arg)
+		}
+		return err
+	}
+
+	return writeGraph(out, graph, hflags.output)
+}
+
+func export(
+	ctx context.Context,
+	flags *flagpole,
+	hflags *headlessFlags,
+	pool *kubeclient.ClientPool,
+	resource string,
+	out io.Writer,
+) error {
+	kctx := pool.CurrentContext()
+
+	src, preset, err := prepareGraph(ctx, flags, hflags, kctx)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := resolveResource(kctx, src, resource)
+	if err != nil {
+		return err
+	}
+
+	client, err := kctx.DynamicClient()
+	if err != nil {
+		return err
+	}
+
+	list, err := client.
+		Resource(mapping.Resource).
+		Namespace(namespaceFor(flags, hflags, kctx, mapping)).
+		List(ctx, metav1.ListOptions{
+			LabelSelector: hflags.labelSelector,
+			FieldSelector: hflags.fieldSelector,
+		})
+	if err != nil {
+		if apierrors.IsForbidden(err) {
+			return fmt.Errorf("not allowed to list %s", mapping.Resource.String())
+		}
+		return err
+	}
+
+	objs := []*unstructured.Unstructured{}
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+
+	return writeGraph(out, preset.Engine(src).Connect(objs), hflags.output)
+}
+
+func watch(
+	ctx context.Context,
+	flags *flagpole,
+	hflags *headlessFlags,
+	pool *kubeclient.ClientPool,
+	arg string,
+) error {
+	resource, name, _ := strings.Cut(arg, "/")
+
+	kctx := pool.CurrentContext()
+
+	src, err := relations.NewClientSource(kctx)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := resolveResource(kctx, src, resource)
+	if err != nil {
+		return err
+	}
+
+	params, err := json.Marshal(map[string]string{
+		"context":       kctx.Name(),
+		"group":         mapping.Resource.Group,
+		"version":       mapping.Resource.Version,
+		"resource":      mapping.Resource.Resource,
+		"namespace":     namespaceFor(flags, hflags, kctx, mapping),
+		"name":          name,
+		"labelSelector": hflags.labelSelector,
+		"fieldSelector": hflags.fieldSelector,
+	})
+	if err != nil {
+		return err
+	}
+
+	reply := make(chan stream.Message)
+	done := make(chan error, 1)
+	go func() {
+		done <- streamkubeobjects.NewWatchHandler(pool, nil).Handle(ctx, streamrpc.Call{
+			ID:     "cli",
+			Method: streamkubeobjects.Watch,
+			Params: params,
+		}, reply)
+	}()
+
+	for {
+		select {
+		case msg := <-reply:
+			if _, err := fmt.Fprintln(os.Stdout, string(msg)); err != nil {
+				return err
+			}
+		case err := <-done:
+			return err
+		}
+	}
+}
+
+func prepareGraph(
+	ctx context.Context,
+	flags *flagpole,
+	hflags *headlessFlags,
+	kctx *kubeclient.Context,
+) (*relations.ClientSource, relations.Preset, error) {
+	if hflags.output != "json" {
+		if _, err := relations.ParseExportFormat(hflags.output); err != nil {
+			return nil, relations.Preset{}, err
+		}
+	}
+
+	registry, err := presets.NewRegistry(flags.presetsDir, logrus.NewEntry(logrus.StandardLogger()))
+	if err != nil {
+		return nil, relations.Preset{}, err
+	}
+
+	preset, err := registry.Lookup(ctx, kctx, hflags.preset)
+	if err != nil {
+		if errors.Is(err, presets.ErrNotFound) {
+			return nil, relations.Preset{}, fmt.Errorf("unknown preset %q", hflags.preset)
+		}
+		return nil, relations.Preset{}, err
+	}
+
+	src, err := relations.NewClientSource(kctx)
+	if err != nil {
+		return nil, relations.Preset{}, err
+	}
+
+	return src, preset, nil
+}
+
+// resolveResource understands the same resource notations as kubectl
+// does: kinds, plural and singular names, short names, and the
+// <resource>.<group> form (e.g., deploy, Deployment, deployments.apps).
+func resolveResource(kctx *kubeclient.Context, src *relations.ClientSource, resource string) (*meta.RESTMapping, error) {
+	discoveryClient, err := kctx.DiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	mapper := restmapper.NewShortcutExpander(src.Mapper(), discoveryClient, nil)
+
+	gvr, gr := schema.ParseResourceArg(resource)
+	var gvk schema.GroupVersionKind
+	if gvr != nil {
+		gvk, err = mapper.KindFor(*gvr)
+	}
+	if gvk.Empty() {
+		gvk, err = mapper.KindFor(gr.WithVersion(""))
+	}
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("unknown resource %q", resource)
+		}
+		return nil, err
+	}
+
+	return mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// namespaceFor returns the namespace to look in: --namespace, then the
+// context's namespace, then "default". Cluster-scoped resources and
+// --all-namespaces get an empty namespace.
+func namespaceFor(flags *flagpole, hflags *headlessFlags, kctx *kubeclient.Context, mapping *meta.RESTMapping) string {
+	if hflags.allNamespaces || mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return ""
+	}
+	if flags.Namespace != nil && *flags.Namespace != "" {
+		return *flags.Namespace
+	}
+	if kctx.Namespace() != "" {
+		return kctx.Namespace()
+	}
+	return "default"
+}
+
+func writeGraph(w io.Writer, graph *relations.Graph, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(graph)
+	}
+
+	format, err := relations.ParseExportFormat(output)
+	if err != nil {
+		return err
+	}
+	return relations.Export(w, graph, format)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/offline"
+	"github.com/iximiuz/kexp/relations"
+)
+
+// newHeadlessTestPool serves the offline fixtures (with the short names
+// a real API server reports) as the current context.
+func newHeadlessTestPool(t *testing.T, namespace string) (*kubeclient.ClientPool, *relations.ClientSource) {
+	t.Helper()
+
+	objs, err := offline.LoadFixtures("offline/testdata/cluster")
+	if err != nil {
+		t.Fatalf("LoadFixtures() failed: %v", err)
+	}
+
+	shortNames := map[string][]string{
+		"deployments": {"deploy"},
+		"pods":        {"po"},
+		"services":    {"svc"},
+		"configmaps":  {"cm"},
+		"namespaces":  {"ns"},
+	}
+	resources := offline.ResourcesOf(objs, nil)
+	for _, list := range resources {
+		for i := range list.APIResources {
+			list.APIResources[i].ShortNames = shortNames[list.APIResources[i].Name]
+		}
+	}
+
+	cluster, err := offline.NewCluster(nil, resources, objs)
+	if err != nil {
+		t.Fatalf("NewCluster() failed: %v", err)
+	}
+
+	pool := kubeclient.NewPool()
+	if err := pool.AddStatic("fake", "fake", "", namespace, cluster.Clients()); err != nil {
+		t.Fatalf("AddStatic() failed: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	src, err := relations.NewClientSource(pool.CurrentContext())
+	if err != nil {
+		t.Fatalf("NewClientSource() failed: %v", err)
+	}
+	return pool, src
+}
+
+func newHeadlessTestFlags(namespace string) *flagpole {
+	flags := &flagpole{ConfigFlags: genericclioptions.NewConfigFlags(false)}
+	*flags.Namespace = namespace
+	return flags
+}
+
+func TestResolveResource(t *testing.T) {
+	pool, src := newHeadlessTestPool(t, "default")
+
+	tests := []struct {
+		resource string
+		want     string // GroupVersionResource
+		wantErr  bool
+	}{
+		{resource: "deployments", want: "apps/v1, Resource=deployments"},
+		{resource: "deployment", want: "apps/v1, Resource=deployments"},
+		{resource: "Deployment", want: "apps/v1, Resource=deployments"},
+		{resource: "deploy", want: "apps/v1, Resource=deployments"},
+		{resource: "deployments.apps", want: "apps/v1, Resource=deployments"},
+		{resource: "deployments.v1.apps", want: "apps/v1, Resource=deployments"},
+		{resource: "po", want: "/v1, Resource=pods"},
+		{resource: "svc", want: "/v1, Resource=services"},
+		{resource: "ns", want: "/v1, Resource=namespaces"},
+		{resource: "replicasets", want: "apps/v1, Resource=replicasets"},
+		{resource: "statefulsets", wantErr: true},
+		{resource: "deployments.batch", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			mapping, err := resolveResource(pool.CurrentContext(), src, tt.resource)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveResource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && mapping.Resource.String() != tt.want {
+				t.Errorf("resolveResource() = %s, want %s", mapping.Resource, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceFor(t *testing.T) {
+	tests := []struct {
+		name             string
+		resource         string
+		flagNamespace    string
+		contextNamespace string
+		allNamespaces    bool
+		want             string
+	}{
+		{"flag first", "pods", "kube-system", "web", false, "kube-system"},
+		{"then context", "pods", "", "web", false, "web"},
+		{"then default", "pods", "", "", false, "default"},
+		{"all namespaces", "pods", "kube-system", "web", true, ""},
+		{"cluster-scoped", "namespaces", "kube-system", "web", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, src := newHeadlessTestPool(t, tt.contextNamespace)
+			kctx := pool.CurrentContext()
+
+			mapping, err := resolveResource(kctx, src, tt.resource)
+			if err != nil {
+				t.Fatalf("resolveResource() failed: %v", err)
+			}
+
+			got := namespaceFor(newHeadlessTestFlags(tt.flagNamespace), &headlessFlags{allNamespaces: tt.allNamespaces}, kctx, mapping)
+			if got != tt.want {
+				t.Errorf("namespaceFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraph(t *testing.T) {
+	tests := []struct {
+		name      string
+		arg       string
+		hflags    headlessFlags
+		namespace string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name:   "tree",
+			arg:    "deploy/web",
+			hflags: headlessFlags{output: "tree", depth: relations.DefaultDepth},
+			want: []string{`Deployment/default/web
+├── → owns: ReplicaSet/default/web-5d8f7c9b6
+│   └── → owns: Pod/default/web-5d8f7c9b6-x2v7q
+│       └── → references: ConfigMap/default/web-config
+└── ← contains: Namespace/default
+    └── → contains: Service/default/web
+`},
+		},
+		{
+			name:   "mermaid",
+			arg:    "svc/web",
+			hflags: headlessFlags{output: "mermaid", depth: 1},
+			want:   []string{"graph LR\n", `["Service/web"]`, `["Pod/web-5d8f7c9b6-x2v7q"]`, "-->|selects|"},
+		},
+		{
+			name:   "cluster-scoped",
+			arg:    "ns/kube-system",
+			hflags: headlessFlags{output: "dot", depth: 1},
+			want:   []string{`"core/Namespace//kube-system" [label="Namespace/kube-system", style=bold];`},
+		},
+		{
+			name:      "wrong namespace",
+			arg:       "deploy/web",
+			hflags:    headlessFlags{output: "tree", depth: 1},
+			namespace: "kube-system",
+			wantErr:   "deploy/web not found",
+		},
+		{
+			name:    "no name",
+			arg:     "deploy",
+			hflags:  headlessFlags{output: "tree", depth: 1},
+			wantErr: "expected <kind/name> argument",
+		},
+		{
+			name:    "too deep",
+			arg:     "deploy/web",
+			hflags:  headlessFlags{output: "tree", depth: relations.MaxDepth + 1},
+			wantErr: "depth must be between",
+		},
+		{
+			name:    "unknown format",
+			arg:     "deploy/web",
+			hflags:  headlessFlags{output: "png", depth: 1},
+			wantErr: `unknown export format "png"`,
+		},
+		{
+			name:    "unknown preset",
+			arg:     "deploy/web",
+			hflags:  headlessFlags{output: "tree", depth: 1, preset: "nope"},
+			wantErr: `unknown preset "nope"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, _ := newHeadlessTestPool(t, "default")
+
+			var out bytes.Buffer
+			err := graph(context.Background(), newHeadlessTestFlags(tt.namespace), &tt.hflags, pool, tt.arg, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("graph() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("graph() failed: %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("graph() output doesn't contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestGraphJSON(t *testing.T) {
+	pool, _ := newHeadlessTestPool(t, "default")
+
+	var out bytes.Buffer
+	if err := graph(context.Background(), newHeadlessTestFlags(""), &headlessFlags{output: "json", depth: 1}, pool, "deployments.apps/web", &out); err != nil {
+		t.Fatalf("graph() failed: %v", err)
+	}
+
+	var got relations.Graph
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output isn't a JSON graph: %v", err)
+	}
+	if got.Root != "apps/Deployment/default/web" || len(got.Nodes) != 3 {
+		t.Errorf("graph = %+v, want the deployment, its replica set, and its namespace", got)
+	}
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		hflags   headlessFlags
+		want     []string
+		dontWant []string
+	}{
+		{
+			name:     "namespace",
+			resource: "pods",
+			hflags:   headlessFlags{output: "tree"},
+			want:     []string{"Pod/default/web-5d8f7c9b6-x2v7q\n"},
+		},
+		{
+			// Only the exported objects are connected.
+			name:     "not connected",
+			resource: "deploy",
+			hflags:   headlessFlags{output: "dot"},
+			want:     []string{`"apps/Deployment/default/web" [label="Deployment/web"`},
+			dontWant: []string{"ReplicaSet", "->"},
+		},
+		{
+			name:     "label selector",
+			resource: "configmaps",
+			hflags:   headlessFlags{output: "tree", labelSelector: "app=nope"},
+			dontWant: []string{"web-config"},
+		},
+		{
+			name:     "all namespaces",
+			resource: "namespaces",
+			hflags:   headlessFlags{output: "mermaid", allNamespaces: true},
+			want:     []string{`["Namespace/default"]`, `["Namespace/kube-system"]`},
+			dontWant: []string{"-->"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, _ := newHeadlessTestPool(t, "default")
+
+			var out bytes.Buffer
+			if err := export(context.Background(), newHeadlessTestFlags(""), &tt.hflags, pool, tt.resource, &out); err != nil {
+				t.Fatalf("export() failed: %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("export() output doesn't contain %q:\n%s", want, out.String())
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(out.String(), dontWant) {
+					t.Errorf("export() output contains %q:\n%s", dontWant, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package kubeclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Changes to these resources (may) change the set of served API resources.
+var discoveryInvalidationResources = []schema.GroupVersionResource{
+	{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
+	{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"},
+}
+
+// How often the cached discovery data is dropped if the changes to
+// CRDs or APIServices can't be watched (e.g., no RBAC permissions).
+const discoveryCacheTTL = 5 * time.Minute
+
+const accessReviewTimeout = 10 * time.Second
+
+// startDiscoveryInvalidation watches CRDs and APIServices and drops
+// the cached discovery data of the context (and of all its impersonated
+// copies) on every change. The resources the context isn't allowed to
+// list and watch are not watched - the data just expires periodically
+// then. Must be called (once) on a root context.
+func (c *Context) startDiscoveryInvalidation() {
+	if c.static {
+		return
+	}
+
+	logger := logrus.
+		WithField("module", "kubeclient/discovery").
+		WithField("context", c.name)
+
+	// Access reviews take a while - don't hold up the first discovery.
+	go func() {
+		watchable := c.watchableDiscoveryInvalidationResources(logger)
+		if len(watchable) > 0 && !c.watchDiscoveryInvalidationResources(watchable, logger) {
+			watchable = nil
+		}
+
+		if len(watchable) < len(discoveryInvalidationResources) {
+			logger.
+				WithField("ttl", discoveryCacheTTL.String()).
+				Info("Can't watch CRDs and APIServices - the discovery cache will expire periodically")
+			c.expireDiscovery(discoveryCacheTTL)
+		}
+	}()
+}
+
+func (c *Context) watchDiscoveryInvalidationResources(
+	resources []schema.GroupVersionResource,
+	logger *logrus.Entry,
+) bool {
+	client, err := c.DynamicClient()
+	if err != nil {
+		logger.
+			WithError(err).
+			Warn("Couldn't start discovery cache invalidation")
+		return false
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	for _, gvr := range resources {
+		_, err := factory.ForResource(gvr).Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(_ interface{}, isInInitialList bool) {
+				if !isInInitialList {
+					c.invalidateDiscovery()
+				}
+			},
+			UpdateFunc: func(_, _ interface{}) {
+				c.invalidateDiscovery()
+			},
+			DeleteFunc: func(_ interface{}) {
+				c.invalidateDiscovery()
+			},
+		})
+		if err != nil {
+			logger.
+				WithError(err).
+				WithField("resource", gvr.String()).
+				Warn("Couldn't watch resource for discovery cache invalidation")
+			return false
+		}
+	}
+
+	factory.Start(c.done)
+	return true
+}
+
+// watchableDiscoveryInvalidationResources returns the resources the
+// context is allowed to list and watch. Without the permissions, the
+// informers would retry (and log errors) forever.
+func (c *Context) watchableDiscoveryInvalidationResources(logger *logrus.Entry) []schema.GroupVersionResource {
+	clientset, err := c.Clientset()
+	if err != nil {
+		logger.
+			WithError(err).
+			Warn("Couldn't check discovery cache invalidation permissions")
+		return nil
+	}
+
+	var watchable []schema.GroupVersionResource
+	for _, gvr := range discoveryInvalidationResources {
+		allowed, err := canListAndWatch(clientset, gvr)
+		if err != nil {
+			logger.
+				WithError(err).
+				WithField("resource", gvr.String()).
+				Warn("Couldn't check discovery cache invalidation permissions")
+			continue
+		}
+		if allowed {
+			watchable = append(watchable, gvr)
+		}
+	}
+	return watchable
+}
+
+func canListAndWatch(clientset kubernetes.Interface, gvr schema.GroupVersionResource) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), accessReviewTimeout)
+	defer cancel()
+
+	for _, verb := range []string{"list", "watch"} {
+		review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(
+			ctx,
+			&authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Group:    gvr.Group,
+						Version:  gvr.Version,
+						Resource: gvr.Resource,
+						Verb:     verb,
+					},
+				},
+			},
+			metav1.CreateOptions{},
+		)
+		if err != nil {
+			return false, err
+		}
+		if !review.Status.Allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// expireDiscovery drops the cached discovery data every ttl
+// until the context is removed from the pool.
+func (c *Context) expireDiscovery(ttl time.Duration) {
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.invalidateDiscovery()
+		}
+	}
+}
+
+func (c *Context) invalidateDiscovery() {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	if c.discoveryClient != nil {
+		c.discoveryClient.Invalidate()
+	}
+
+	for _, ictx := range c.impersonated.all() {
+		ictx.mux.RLock()
+		if ictx.discoveryClient != nil {
+			ictx.discoveryClient.Invalidate()
+		}
+		ictx.mux.RUnlock()
+	}
+}
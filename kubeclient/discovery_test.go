@@ -0,0 +1,52 @@
+package kubeclient
+
+import (
+	"errors"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCanListAndWatch(t *testing.T) {
+	crds := discoveryInvalidationResources[0]
+
+	tests := []struct {
+		name    string
+		allowed map[string]bool
+		err     error
+		want    bool
+		wantErr bool
+	}{
+		{"list and watch", map[string]bool{"list": true, "watch": true}, nil, true, false},
+		{"list only", map[string]bool{"list": true}, nil, false, false},
+		{"nothing", nil, nil, false, false},
+		{"review failed", nil, errors.New("forbidden"), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				attrs := review.Spec.ResourceAttributes
+				if attrs.Group != crds.Group || attrs.Resource != crds.Resource {
+					t.Errorf("review for %s/%s, want %s", attrs.Group, attrs.Resource, crds.String())
+				}
+
+				review.Status.Allowed = tt.allowed[attrs.Verb]
+				return true, review, tt.err
+			})
+
+			got, err := canListAndWatch(clientset, crds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("canListAndWatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("canListAndWatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
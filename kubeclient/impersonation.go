@@ -0,0 +1,109 @@
+package kubeclient
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Every impersonated identity gets its own clients (and caches),
+	// so only so many of the recently used ones are kept.
+	maxImpersonatedContexts = 100
+	impersonatedContextTTL  = 30 * time.Minute
+)
+
+type impersonationContextKey struct{}
+
+// Impersonation describes an identity the Kubernetes API requests
+// should be performed on behalf of (see Impersonate-* headers).
+type Impersonation struct {
+	User   string
+	Groups []string
+}
+
+func (i Impersonation) IsZero() bool {
+	return i.User == "" && len(i.Groups) == 0
+}
+
+// Cache key - groups are order-insensitive.
+func (i Impersonation) key() string {
+	groups := append([]string{}, i.Groups...)
+	sort.Strings(groups)
+	return i.User + "\x00" + strings.Join(groups, "\x00")
+}
+
+func WithImpersonation(ctx context.Context, imp Impersonation) context.Context {
+	return context.WithValue(ctx, impersonationContextKey{}, imp)
+}
+
+func ImpersonationFrom(ctx context.Context) (Impersonation, bool) {
+	imp, ok := ctx.Value(impersonationContextKey{}).(Impersonation)
+	return imp, ok && !imp.IsZero()
+}
+
+// impersonatedCache is an LRU cache of impersonated copies of a context.
+// The copies not used for impersonatedContextTTL are dropped too.
+// Not safe for concurrent use (guarded by the context's mux).
+type impersonatedCache struct {
+	entries map[string]*list.Element
+	order   list.List // Of *impersonatedEntry, the most recently used first.
+}
+
+type impersonatedEntry struct {
+	key      string
+	ctx      *Context
+	lastUsed time.Time
+}
+
+func (c *impersonatedCache) get(key string) *Context {
+	c.expire()
+
+	elem, found := c.entries[key]
+	if !found {
+		return nil
+	}
+
+	elem.Value.(*impersonatedEntry).lastUsed = time.Now()
+	c.order.MoveToFront(elem)
+	return elem.Value.(*impersonatedEntry).ctx
+}
+
+func (c *impersonatedCache) add(key string, ctx *Context) {
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+	}
+
+	c.entries[key] = c.order.PushFront(&impersonatedEntry{key: key, ctx: ctx, lastUsed: time.Now()})
+	for c.order.Len() > maxImpersonatedContexts {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *impersonatedCache) all() []*Context {
+	ctxs := make([]*Context, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		ctxs = append(ctxs, elem.Value.(*impersonatedEntry).ctx)
+	}
+	return ctxs
+}
+
+func (c *impersonatedCache) expire() {
+	for elem := c.order.Back(); elem != nil; elem = c.order.Back() {
+		if time.Since(elem.Value.(*impersonatedEntry).lastUsed) < impersonatedContextTTL {
+			return
+		}
+		c.remove(elem)
+	}
+}
+
+func (c *impersonatedCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*impersonatedEntry)
+	delete(c.entries, entry.key)
+
+	// Requests still using the copy keep working, but its
+	// informers (if any) don't outlive it for long.
+	entry.ctx.stopInformers(0)
+}
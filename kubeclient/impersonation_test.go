@@ -0,0 +1,80 @@
+package kubeclient
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+func newImpersonationTestContext() *Context {
+	return &Context{
+		name:   "prod",
+		config: &rest.Config{Host: "https://prod.example.com"},
+		done:   make(chan struct{}),
+	}
+}
+
+func TestImpersonate(t *testing.T) {
+	kctx := newImpersonationTestContext()
+
+	alice, err := kctx.Impersonate(Impersonation{User: "alice", Groups: []string{"devs", "ops"}})
+	if err != nil {
+		t.Fatalf("Impersonate() failed: %v", err)
+	}
+	if alice.config.Impersonate.UserName != "alice" || alice.config.Impersonate.UserName == kctx.config.Impersonate.UserName {
+		t.Errorf("impersonated config = %+v, want alice (and the parent's config intact)", alice.config.Impersonate)
+	}
+
+	// Groups are order-insensitive.
+	again, _ := kctx.Impersonate(Impersonation{User: "alice", Groups: []string{"ops", "devs"}})
+	if again != alice {
+		t.Error("Impersonate() didn't reuse the cached copy")
+	}
+
+	if same, _ := kctx.Impersonate(Impersonation{}); same != kctx {
+		t.Error("Impersonate() with no identity must return the context itself")
+	}
+
+	if _, err := kctx.Impersonate(Impersonation{Groups: []string{"devs"}}); err == nil {
+		t.Error("Impersonate() with groups only succeeded, want error")
+	}
+}
+
+func TestImpersonateBounded(t *testing.T) {
+	kctx := newImpersonationTestContext()
+
+	first, _ := kctx.Impersonate(Impersonation{User: "user-0"})
+	for i := 1; i <= maxImpersonatedContexts; i++ {
+		if _, err := kctx.Impersonate(Impersonation{User: fmt.Sprintf("user-%d", i)}); err != nil {
+			t.Fatalf("Impersonate() failed: %v", err)
+		}
+	}
+
+	if got := len(kctx.impersonated.all()); got != maxImpersonatedContexts {
+		t.Errorf("cached copies = %d, want %d", got, maxImpersonatedContexts)
+	}
+
+	// The least recently used one is gone.
+	if again, _ := kctx.Impersonate(Impersonation{User: "user-0"}); again == first {
+		t.Error("the least recently used copy wasn't evicted")
+	}
+}
+
+func TestImpersonateExpired(t *testing.T) {
+	kctx := newImpersonationTestContext()
+
+	alice, _ := kctx.Impersonate(Impersonation{User: "alice"})
+	bob, _ := kctx.Impersonate(Impersonation{User: "bob"})
+
+	kctx.impersonated.entries[Impersonation{User: "alice"}.key()].Value.(*impersonatedEntry).lastUsed =
+		time.Now().Add(-impersonatedContextTTL)
+
+	if again, _ := kctx.Impersonate(Impersonation{User: "alice"}); again == alice {
+		t.Error("the expired copy was reused")
+	}
+	if again, _ := kctx.Impersonate(Impersonation{User: "bob"}); again != bob {
+		t.Error("the recently used copy wasn't reused")
+	}
+}
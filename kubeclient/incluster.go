@@ -0,0 +1,36 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// The pseudo-context name for the cluster kexp runs in.
+	InClusterContext = "in-cluster"
+
+	inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// AddInCluster adds the in-cluster pseudo-context - the pod's
+// ServiceAccount talking to the cluster the pod runs in.
+func (p *ClientPool) AddInCluster(ctx context.Context) error {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return fmt.Errorf("couldn't load in-cluster config: %w", err)
+	}
+
+	// The pod's own namespace is the most sensible default.
+	namespace := "default"
+	if data, err := os.ReadFile(inClusterNamespaceFile); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			namespace = ns
+		}
+	}
+
+	return p.Add(ctx, InClusterContext, "serviceaccount", InClusterContext, namespace, config)
+}
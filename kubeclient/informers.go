@@ -0,0 +1,248 @@
+package kubeclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// Informers not used for this long are stopped (and the resources
+	// that couldn't be listed are checked again).
+	informerIdleTimeout = 10 * time.Minute
+
+	informerJanitorInterval = time.Minute
+
+	// Starting an informer doesn't depend on the context of
+	// the request that happened to need it first.
+	informerStartTimeout = 30 * time.Second
+)
+
+type informerKey struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+type sharedInformer struct {
+	// Closed when the fields below are set.
+	ready chan struct{}
+
+	// Set if the informer couldn't be started (the waiters get it too,
+	// and the next caller tries again).
+	err error
+
+	// Nil if the objects can't be listed.
+	informer cache.SharedIndexInformer
+	stop     chan struct{}
+
+	// Guarded by the context's informersMux.
+	lastUsed time.Time
+
+	// Subscribed informers aren't stopped when idle. Guarded by the
+	// context's informersMux.
+	subscribers int
+}
+
+// Store returns the cache of the resource's objects in the namespace
+// (or in all namespaces if empty) shared by all callers - the informer
+// behind it is started by the first call and stopped after it hasn't been
+// used for a while. Returns nil (and no error) if the user isn't allowed
+// to list the objects. Blocks until the cache is synced.
+func (c *Context) Store(
+	ctx context.Context,
+	gvr schema.GroupVersionResource,
+	namespace string,
+) (cache.Store, error) {
+	si, err := c.sharedInformer(ctx, informerKey{gvr: gvr, namespace: namespace})
+	if err != nil || si.informer == nil {
+		return nil, err
+	}
+	return si.informer.GetStore(), nil
+}
+
+// Subscribe is like Store but also calls the onChange callback on every
+// change of the objects (including the initial ones) until the returned
+// unsubscribe function is called. Returns nil and a no-op function if the
+// user isn't allowed to list the objects.
+func (c *Context) Subscribe(
+	ctx context.Context,
+	gvr schema.GroupVersionResource,
+	namespace string,
+	onChange func(),
+) (cache.Store, func(), error) {
+	si, err := c.sharedInformer(ctx, informerKey{gvr: gvr, namespace: namespace})
+	if err != nil || si.informer == nil {
+		return nil, func() {}, err
+	}
+
+	c.informersMux.Lock()
+	si.subscribers++
+	c.informersMux.Unlock()
+
+	unsubscribe := func() {
+		c.informersMux.Lock()
+		si.subscribers--
+		si.lastUsed = time.Now()
+		c.informersMux.Unlock()
+	}
+
+	registration, err := si.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(_ interface{}) { onChange() },
+		UpdateFunc: func(_, _ interface{}) { onChange() },
+		DeleteFunc: func(_ interface{}) { onChange() },
+	})
+	if err != nil {
+		unsubscribe()
+		return nil, func() {}, err
+	}
+
+	var once sync.Once
+	return si.informer.GetStore(), func() {
+		once.Do(func() {
+			_ = si.informer.RemoveEventHandler(registration)
+			unsubscribe()
+		})
+	}, nil
+}
+
+// sharedInformer returns the synced shared informer for the key
+// (starting it if needed).
+func (c *Context) sharedInformer(ctx context.Context, key informerKey) (*sharedInformer, error) {
+
+	c.informersMux.Lock()
+	si, found := c.informers[key]
+	if !found {
+		si = &sharedInformer{ready: make(chan struct{})}
+		if c.informers == nil {
+			c.informers = make(map[informerKey]*sharedInformer)
+		}
+		c.informers[key] = si
+
+		if !c.informersJanitor {
+			c.informersJanitor = true
+			go c.stopIdleInformers()
+		}
+	}
+	si.lastUsed = time.Now()
+	c.informersMux.Unlock()
+
+	if !found {
+		go c.startInformer(key, si)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-si.ready:
+	}
+
+	if si.err != nil {
+		return nil, si.err
+	}
+	if si.informer == nil {
+		return si, nil
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), si.informer.HasSynced) {
+		return nil, ctx.Err()
+	}
+	return si, nil
+}
+
+func (c *Context) startInformer(key informerKey, si *sharedInformer) {
+	defer close(si.ready)
+
+	if si.err = c.newInformer(key, si); si.err != nil {
+		c.informersMux.Lock()
+		delete(c.informers, key)
+		c.informersMux.Unlock()
+	}
+}
+
+func (c *Context) newInformer(key informerKey, si *sharedInformer) error {
+	client, err := c.DynamicClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), informerStartTimeout)
+	defer cancel()
+
+	// An informer for a resource that can't be listed would never sync.
+	_, err = client.Resource(key.gvr).Namespace(key.namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	informer := dynamicinformer.NewFilteredDynamicInformer(
+		client,
+		key.gvr,
+		key.namespace,
+		0,
+		cache.Indexers{},
+		nil,
+	).Informer()
+
+	si.informer = informer
+	si.stop = make(chan struct{})
+	go informer.Run(si.stop)
+
+	return nil
+}
+
+// stopIdleInformers runs until the context is removed from the pool
+// (or there are no informers left).
+func (c *Context) stopIdleInformers() {
+	ticker := time.NewTicker(informerJanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			c.stopInformers(0)
+			return
+		case <-ticker.C:
+			if c.stopInformers(informerIdleTimeout) == 0 {
+				return
+			}
+		}
+	}
+}
+
+// stopInformers stops the informers not used for the idleTimeout
+// and returns the number of the remaining ones.
+func (c *Context) stopInformers(idleTimeout time.Duration) int {
+	c.informersMux.Lock()
+	defer c.informersMux.Unlock()
+
+	for key, si := range c.informers {
+		select {
+		case <-si.ready:
+		default:
+			continue // Still starting.
+		}
+
+		if idleTimeout > 0 && (si.subscribers > 0 || time.Since(si.lastUsed) < idleTimeout) {
+			continue
+		}
+
+		if si.stop != nil {
+			close(si.stop)
+		}
+		delete(c.informers, key)
+	}
+
+	if len(c.informers) == 0 {
+		c.informersJanitor = false
+	}
+	return len(c.informers)
+}
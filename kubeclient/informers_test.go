@@ -0,0 +1,159 @@
+package kubeclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func newInformersTestContext(listErr error) *Context {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapsGVR: "ConfigMapList"},
+	)
+	if listErr != nil {
+		client.PrependReactor("list", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, listErr
+		})
+	}
+
+	return &Context{
+		name:          "prod",
+		static:        true,
+		dynamicClient: client,
+		done:          make(chan struct{}),
+	}
+}
+
+func TestStore(t *testing.T) {
+	kctx := newInformersTestContext(nil)
+	defer close(kctx.done)
+
+	store, err := kctx.Store(context.Background(), configMapsGVR, "default")
+	if err != nil || store == nil {
+		t.Fatalf("Store() = %v, %v, want a store", store, err)
+	}
+
+	again, _ := kctx.Store(context.Background(), configMapsGVR, "default")
+	if again != store {
+		t.Error("Store() didn't reuse the shared informer")
+	}
+}
+
+func TestStoreForbidden(t *testing.T) {
+	kctx := newInformersTestContext(apierrors.NewForbidden(configMapsGVR.GroupResource(), "", errors.New("nope")))
+	defer close(kctx.done)
+
+	store, err := kctx.Store(context.Background(), configMapsGVR, "default")
+	if store != nil || err != nil {
+		t.Errorf("Store() = %v, %v, want nil, nil", store, err)
+	}
+}
+
+// Every concurrent caller gets the error - not just the one that started
+// the informer - and a failed start isn't cached.
+func TestStoreError(t *testing.T) {
+	kctx := newInformersTestContext(errors.New("connection refused"))
+	defer close(kctx.done)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = kctx.Store(context.Background(), configMapsGVR, "default")
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("Store() #%d succeeded, want error", i)
+		}
+	}
+
+	kctx.informersMux.Lock()
+	defer kctx.informersMux.Unlock()
+	if len(kctx.informers) != 0 {
+		t.Errorf("informers = %v, want the failed one forgotten", kctx.informers)
+	}
+}
+
+// The first caller giving up doesn't fail the informer for the others.
+func TestStoreCallerCancelled(t *testing.T) {
+	kctx := newInformersTestContext(nil)
+	defer close(kctx.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := kctx.Store(ctx, configMapsGVR, "default"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Store() error = %v, want context.Canceled", err)
+	}
+
+	store, err := kctx.Store(context.Background(), configMapsGVR, "default")
+	if err != nil || store == nil {
+		t.Errorf("Store() = %v, %v, want a store", store, err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	kctx := newInformersTestContext(nil)
+	defer close(kctx.done)
+
+	changes := make(chan struct{}, 10)
+	store, unsubscribe, err := kctx.Subscribe(context.Background(), configMapsGVR, "default", func() {
+		changes <- struct{}{}
+	})
+	if err != nil || store == nil {
+		t.Fatalf("Subscribe() = %v, %v, want a store", store, err)
+	}
+
+	// Subscribed informers aren't stopped when idle.
+	kctx.informersMux.Lock()
+	for _, si := range kctx.informers {
+		si.lastUsed = time.Now().Add(-2 * informerIdleTimeout)
+	}
+	kctx.informersMux.Unlock()
+	if kctx.stopInformers(informerIdleTimeout) != 1 {
+		t.Fatal("stopInformers() stopped a subscribed informer")
+	}
+
+	client, _ := kctx.DynamicClient()
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetName("web-config")
+	if _, err := client.Resource(configMapsGVR).Namespace("default").Create(context.Background(), cm, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("onChange wasn't called")
+	}
+
+	unsubscribe()
+	unsubscribe() // Idempotent.
+
+	kctx.informersMux.Lock()
+	for _, si := range kctx.informers {
+		if si.subscribers != 0 {
+			t.Errorf("subscribers = %d after unsubscribe, want 0", si.subscribers)
+		}
+	}
+	kctx.informersMux.Unlock()
+}
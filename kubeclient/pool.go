@@ -9,7 +9,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
@@ -21,14 +23,28 @@ type ClientPool struct {
 
 	contexts map[string]*Context
 	current  *Context
+
+	rateLimit  RateLimit
+	rateLimits map[string]RateLimit
 }
 
 func NewPool() *ClientPool {
 	return &ClientPool{
-		contexts: make(map[string]*Context),
+		contexts:  make(map[string]*Context),
+		rateLimit: DefaultRateLimit,
 	}
 }
 
+// SetRateLimits configures the client-side rate limiting of the contexts
+// added after the call - the default one and the per-context overrides.
+func (p *ClientPool) SetRateLimits(def RateLimit, perContext map[string]RateLimit) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.rateLimit = def
+	p.rateLimits = perContext
+}
+
 func (p *ClientPool) Add(
 	ctx context.Context,
 	context string,
@@ -40,12 +56,23 @@ func (p *ClientPool) Add(
 	p.mux.Lock()
 	defer p.mux.Unlock()
 
+	limit, found := p.rateLimits[context]
+	if !found {
+		limit = p.rateLimit
+	}
+	scheduler := NewScheduler(limit)
+
+	config = rest.CopyConfig(config)
+	config.RateLimiter = scheduler
+
 	kctx := &Context{
 		name:      context,
 		user:      user,
 		cluster:   cluster,
 		namespace: namespace,
 		config:    config,
+		scheduler: scheduler,
+		done:      make(chan struct{}),
 	}
 
 	client, err := kctx.DynamicClient()
@@ -74,6 +101,50 @@ func (p *ClientPool) Add(
 	return nil
 }
 
+// Clients serve a context that isn't backed by a kubeconfig
+// entry (e.g., an offline snapshot of a cluster).
+type Clients struct {
+	Discovery discovery.CachedDiscoveryInterface
+	Dynamic   dynamic.Interface
+	Clientset kubernetes.Interface
+}
+
+// AddStatic adds a context served by the given (usually in-memory) clients.
+func (p *ClientPool) AddStatic(
+	context string,
+	cluster string,
+	clusterUID string,
+	namespace string,
+	clients Clients,
+) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if _, found := p.contexts[context]; found {
+		return fmt.Errorf("context %q already exists", context)
+	}
+
+	kctx := &Context{
+		name:            context,
+		cluster:         cluster,
+		clusterUID:      clusterUID,
+		namespace:       namespace,
+		static:          true,
+		discoveryClient: clients.Discovery,
+		dynamicClient:   clients.Dynamic,
+		clientset:       clients.Clientset,
+		done:            make(chan struct{}),
+	}
+
+	p.contexts[kctx.name] = kctx
+
+	if p.current == nil {
+		p.current = kctx
+	}
+
+	return nil
+}
+
 func (p *ClientPool) SetCurrent(name string) error {
 	p.mux.Lock()
 	defer p.mux.Unlock()
@@ -101,6 +172,31 @@ func (p *ClientPool) Context(name string) (*Context, error) {
 	return nil, errUnknownContext
 }
 
+// ContextFor is like Context, but if the ctx carries an impersonation
+// (see WithImpersonation), the impersonated copy of the context is returned.
+func (p *ClientPool) ContextFor(ctx context.Context, name string) (*Context, error) {
+	kctx, err := p.Context(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if imp, ok := ImpersonationFrom(ctx); ok {
+		return kctx.Impersonate(imp)
+	}
+
+	return kctx, nil
+}
+
+// Close stops all background activities of the pool's contexts.
+func (p *ClientPool) Close() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	for _, c := range p.contexts {
+		c.close()
+	}
+}
+
 func (p *ClientPool) Contexts() (cs []*Context) {
 	p.mux.RLock()
 	defer p.mux.RUnlock()
@@ -136,8 +232,37 @@ type Context struct {
 
 	config *rest.Config
 
-	discoveryClient discovery.DiscoveryInterface
+	// Rate limits the requests (shared with the impersonated copies).
+	scheduler *Scheduler
+
+	// Static contexts have no config - their clients are provided
+	// upfront (see AddStatic).
+	static bool
+
+	// Set only for impersonated copies of a context.
+	parent        *Context
+	impersonation Impersonation
+
+	// Impersonated copies of this context cached per identity
+	// (see impersonatedCache).
+	impersonated impersonatedCache
+
+	discoveryClient discovery.CachedDiscoveryInterface
 	dynamicClient   dynamic.Interface
+	clientset       kubernetes.Interface
+
+	invalidationOnce sync.Once
+
+	// Shared informers (see Store) - a separate lock since
+	// starting one needs the clients (guarded by mux).
+	informersMux     sync.Mutex
+	informers        map[informerKey]*sharedInformer
+	informersJanitor bool
+
+	// Closed when the context is removed from the pool
+	// (impersonated copies share the channel of their parent).
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 func (c *Context) Name() string {
@@ -160,7 +285,75 @@ func (c *Context) Namespace() string {
 	return c.namespace
 }
 
-func (c *Context) DiscoveryClient() (discovery.DiscoveryInterface, error) {
+func (c *Context) Static() bool {
+	return c.static
+}
+
+// Throttling returns nil for static contexts (they aren't rate limited).
+func (c *Context) Throttling() *Throttling {
+	if c.scheduler == nil {
+		return nil
+	}
+
+	t := c.scheduler.Throttling()
+	return &t
+}
+
+func (c *Context) Impersonation() Impersonation {
+	return c.impersonation
+}
+
+// Impersonate returns a copy of the context that performs all
+// Kubernetes API requests on behalf of the given identity.
+func (c *Context) Impersonate(imp Impersonation) (*Context, error) {
+	if imp.IsZero() || c.static {
+		return c, nil
+	}
+	if imp.User == "" {
+		return nil, errors.New("impersonating groups requires a user")
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if ictx := c.impersonated.get(imp.key()); ictx != nil {
+		return ictx, nil
+	}
+
+	config := rest.CopyConfig(c.config)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: imp.User,
+		Groups:   imp.Groups,
+	}
+
+	ictx := &Context{
+		parent:        c,
+		name:          c.name,
+		cluster:       c.cluster,
+		clusterUID:    c.clusterUID,
+		user:          c.user,
+		namespace:     c.namespace,
+		config:        config,
+		scheduler:     c.scheduler,
+		impersonation: imp,
+		done:          c.done,
+	}
+
+	c.impersonated.add(imp.key(), ictx)
+
+	return ictx, nil
+}
+
+// DiscoveryClient returns a memory-cached discovery client. The cache
+// is invalidated automatically when CRDs or APIServices change (or
+// expires periodically if they can't be watched).
+func (c *Context) DiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	root := c
+	if c.parent != nil {
+		root = c.parent
+	}
+	root.invalidationOnce.Do(root.startDiscoveryInvalidation)
+
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
@@ -169,7 +362,7 @@ func (c *Context) DiscoveryClient() (discovery.DiscoveryInterface, error) {
 		if err != nil {
 			return nil, fmt.Errorf("couldn't create discovery client for given config: %w", err)
 		}
-		c.discoveryClient = client
+		c.discoveryClient = memory.NewMemCacheClient(client)
 	}
 
 	return c.discoveryClient, nil
@@ -189,3 +382,24 @@ func (c *Context) DynamicClient() (dynamic.Interface, error) {
 
 	return c.dynamicClient, nil
 }
+
+func (c *Context) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *Context) Clientset() (kubernetes.Interface, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.clientset == nil {
+		client, err := kubernetes.NewForConfig(c.config)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create clientset for given config: %w", err)
+		}
+		c.clientset = client
+	}
+
+	return c.clientset, nil
+}
@@ -0,0 +1,179 @@
+package kubeclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type priorityContextKey struct{}
+
+type Priority int
+
+const (
+	// Lists, watches, discovery - everything nobody is waiting for
+	// (the default for requests with no priority in the ctx).
+	PriorityBackground Priority = iota
+
+	// Requests the user is waiting for (e.g., opening an object).
+	PriorityInteractive
+)
+
+func (p Priority) String() string {
+	if p == PriorityInteractive {
+		return "interactive"
+	}
+	return "background"
+}
+
+func WithPriority(ctx context.Context, prio Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, prio)
+}
+
+func PriorityFrom(ctx context.Context) Priority {
+	prio, _ := ctx.Value(priorityContextKey{}).(Priority)
+	return prio
+}
+
+// Same as client-go's defaults (rest.DefaultQPS and rest.DefaultBurst).
+var DefaultRateLimit = RateLimit{QPS: 5, Burst: 10}
+
+type RateLimit struct {
+	QPS   float32
+	Burst int
+}
+
+// Throttling is a snapshot of a context's scheduler state.
+type Throttling struct {
+	QPS   float32 `json:"qps"`
+	Burst int     `json:"burst"`
+
+	// Requests waiting for their turn right now (per priority).
+	Queued map[string]int `json:"queued"`
+
+	// Requests that had to wait (per priority) and for how long in total.
+	Throttled   map[string]int64 `json:"throttled"`
+	TotalWaitMs map[string]int64 `json:"totalWaitMs"`
+
+	LastThrottledAt *time.Time `json:"lastThrottledAt,omitempty"`
+}
+
+// Scheduler is a client-side rate limiter (flowcontrol.RateLimiter)
+// that lets interactive requests jump the queue - background requests
+// wait while there are interactive ones waiting.
+type Scheduler struct {
+	mux sync.Mutex
+
+	limit   RateLimit
+	limiter *rate.Limiter
+
+	queued          [2]int
+	throttled       [2]int64
+	totalWait       [2]time.Duration
+	lastThrottledAt time.Time
+}
+
+// NewScheduler creates a scheduler. Zero (or negative) QPS means no limit.
+// A burst below 1 (that would block all requests) is treated as 1.
+func NewScheduler(limit RateLimit) *Scheduler {
+	qps := rate.Limit(limit.QPS)
+	if limit.QPS <= 0 {
+		qps = rate.Inf
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return &Scheduler{
+		limit:   limit,
+		limiter: rate.NewLimiter(qps, limit.Burst),
+	}
+}
+
+// Wait blocks until the request (described by the ctx) can be sent.
+func (s *Scheduler) Wait(ctx context.Context) error {
+	prio := PriorityFrom(ctx)
+
+	s.mux.Lock()
+	s.queued[prio]++
+	s.mux.Unlock()
+
+	startedAt := time.Now()
+	defer func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+
+		s.queued[prio]--
+		if waited := time.Since(startedAt); waited > time.Millisecond {
+			s.throttled[prio]++
+			s.totalWait[prio] += waited
+			s.lastThrottledAt = time.Now()
+		}
+	}()
+
+	for {
+		s.mux.Lock()
+		yield := prio == PriorityBackground && s.queued[PriorityInteractive] > 0
+		if !yield && s.limiter.Allow() {
+			s.mux.Unlock()
+			return nil
+		}
+		s.mux.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.retryDelay()):
+		}
+	}
+}
+
+// Roughly the time until the next token is available.
+func (s *Scheduler) retryDelay() time.Duration {
+	if s.limit.QPS <= 0 {
+		return time.Millisecond
+	}
+	return max(time.Duration(float64(time.Second)/float64(s.limit.QPS)), time.Millisecond)
+}
+
+func (s *Scheduler) Accept() {
+	_ = s.Wait(context.Background())
+}
+
+func (s *Scheduler) TryAccept() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.limiter.Allow()
+}
+
+func (s *Scheduler) QPS() float32 {
+	return s.limit.QPS
+}
+
+func (s *Scheduler) Stop() {}
+
+func (s *Scheduler) Throttling() Throttling {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	t := Throttling{
+		QPS:         s.limit.QPS,
+		Burst:       s.limit.Burst,
+		Queued:      map[string]int{},
+		Throttled:   map[string]int64{},
+		TotalWaitMs: map[string]int64{},
+	}
+	for _, prio := range []Priority{PriorityBackground, PriorityInteractive} {
+		t.Queued[prio.String()] = s.queued[prio]
+		t.Throttled[prio.String()] = s.throttled[prio]
+		t.TotalWaitMs[prio.String()] = s.totalWait[prio].Milliseconds()
+	}
+	if !s.lastThrottledAt.IsZero() {
+		last := s.lastThrottledAt
+		t.LastThrottledAt = &last
+	}
+	return t
+}
@@ -0,0 +1,30 @@
+package kubeclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerWait(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit RateLimit
+	}{
+		{"default", DefaultRateLimit},
+		{"no limit", RateLimit{QPS: 0, Burst: 0}},
+		{"zero burst", RateLimit{QPS: 5, Burst: 0}},
+		{"negative burst", RateLimit{QPS: 5, Burst: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if err := NewScheduler(tt.limit).Wait(ctx); err != nil {
+				t.Errorf("Wait() failed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// parseListen applies --listen (if any) on top of --host and --port:
+//
+//	tcp://<host>:<port>
+//	unix:///abs/path/kexp.sock
+//	unix:relative/path/kexp.sock
+func parseListen(flags *flagpole) error {
+	if flags.listen == "" {
+		return nil
+	}
+
+	u, err := url.Parse(flags.listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", flags.listen, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			return fmt.Errorf("invalid listen address %q: %w", flags.listen, err)
+		}
+		flags.host, flags.port = host, port
+
+	case "unix":
+		path := u.Path
+		if u.Opaque != "" {
+			path = u.Opaque
+		}
+		if u.Host != "" || path == "" {
+			return fmt.Errorf("invalid listen address %q - expected unix:///path/to/kexp.sock", flags.listen)
+		}
+		flags.socketPath = path
+
+	default:
+		return fmt.Errorf("invalid listen address %q - expected tcp://<host>:<port> or unix://<path>", flags.listen)
+	}
+
+	return nil
+}
+
+func (f *flagpole) address() string {
+	if f.socketPath != "" {
+		return "unix://" + f.socketPath
+	}
+	return f.scheme() + "://" + net.JoinHostPort(f.host, f.port)
+}
+
+func listen(flags *flagpole) (net.Listener, error) {
+	if flags.socketPath == "" {
+		return net.Listen("tcp", net.JoinHostPort(flags.host, flags.port))
+	}
+
+	mode, err := strconv.ParseUint(flags.socketMode, 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid socket mode %q - expected an octal number (e.g., 0660)", flags.socketMode)
+	}
+
+	if err := removeStaleSocket(flags.socketPath); err != nil {
+		return nil, err
+	}
+
+	// The socket is created with the most restrictive permissions
+	// and only then opened up to the requested mode - no window
+	// for other users to connect in between.
+	oldMask := syscall.Umask(0o177)
+	listener, err := net.Listen("unix", flags.socketPath)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(flags.socketPath, os.FileMode(mode)); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("couldn't set socket permissions: %w", err)
+	}
+
+	return listener, nil
+}
+
+// removeStaleSocket removes the socket file left by a previous
+// (crashed) run, but not a socket of a running server or a regular file.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists and is not a socket", path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+
+	return os.Remove(path)
+}
@@ -8,12 +8,32 @@ import (
 
 type loggingContextKey int
 
-const KeyRequestID loggingContextKey = iota
+const (
+	KeyRequestID loggingContextKey = iota
+	KeyPrincipal
+)
 
 func WithRequestID(ctx context.Context, logger *logrus.Entry) *logrus.Entry {
-	rid, ok := ctx.Value(KeyRequestID).(string)
-	if !ok {
-		rid = "none"
+	return logger.WithField("request_id", RequestID(ctx))
+}
+
+// WithPrincipal adds the authenticated principal (if any) to the logger.
+func WithPrincipal(ctx context.Context, logger *logrus.Entry) *logrus.Entry {
+	if principal := Principal(ctx); principal != "" {
+		return logger.WithField("principal", principal)
+	}
+	return logger
+}
+
+func RequestID(ctx context.Context) string {
+	if rid, ok := ctx.Value(KeyRequestID).(string); ok {
+		return rid
 	}
-	return logger.WithField("request_id", rid)
+	return "none"
+}
+
+// Principal returns the authenticated principal ("" if auth is off).
+func Principal(ctx context.Context) string {
+	principal, _ := ctx.Value(KeyPrincipal).(string)
+	return principal
 }
@@ -2,10 +2,18 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,13 +22,30 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	"github.com/iximiuz/kexp/api"
+	"github.com/iximiuz/kexp/api/auth"
+	restaudit "github.com/iximiuz/kexp/api/rest/audit"
+	restconfig "github.com/iximiuz/kexp/api/rest/config"
 	restkubecontexts "github.com/iximiuz/kexp/api/rest/kube/contexts"
+	restkubediff "github.com/iximiuz/kexp/api/rest/kube/diff"
+	restkubeexport "github.com/iximiuz/kexp/api/rest/kube/export"
 	restkubeobjects "github.com/iximiuz/kexp/api/rest/kube/objects"
+	restkubepermissions "github.com/iximiuz/kexp/api/rest/kube/permissions"
+	restkubepresets "github.com/iximiuz/kexp/api/rest/kube/presets"
+	restkuberelations "github.com/iximiuz/kexp/api/rest/kube/relations"
 	restkuberesources "github.com/iximiuz/kexp/api/rest/kube/resources"
+	restkubeschemas "github.com/iximiuz/kexp/api/rest/kube/schemas"
+	restkubesnapshots "github.com/iximiuz/kexp/api/rest/kube/snapshots"
 	"github.com/iximiuz/kexp/api/stream"
 	streamrpc "github.com/iximiuz/kexp/api/stream/rpc"
 	streamkubeobjects "github.com/iximiuz/kexp/api/stream/rpc/kube/objects"
+	"github.com/iximiuz/kexp/audit"
+	"github.com/iximiuz/kexp/certs"
 	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/offline"
+	"github.com/iximiuz/kexp/policy"
+	"github.com/iximiuz/kexp/presets"
+	"github.com/iximiuz/kexp/recording"
+	"github.com/iximiuz/kexp/snapshot"
 )
 
 var (
@@ -36,6 +61,48 @@ type flagpole struct {
 
 	host string
 	port string
+
+	listen     string
+	socketMode string
+	socketPath string // Parsed from --listen.
+
+	basePath string
+
+	presetsDir string
+
+	snapshots []string
+
+	fakeClusterDir string
+
+	inCluster bool
+
+	record           string
+	recordSecretData bool
+
+	allowedOrigins []string
+	allowedHosts   []string
+	trustedProxies []string
+
+	authMode      string
+	authTokenFile string
+	authHtpasswd  string
+
+	tlsCert       string
+	tlsKey        string
+	tlsSelfSigned bool
+
+	readOnly   bool
+	policyFile string
+
+	auditLog string
+
+	qps        float32
+	burst      int
+	rateLimits []string
+
+	replaySpeed    float64
+	replayMaxPause time.Duration
+	replayLoop     bool
 }
 
 // [--kubeconfig] [--namespace] [--context]
@@ -56,6 +123,43 @@ func main() {
 	flags.AddFlags(cmd.PersistentFlags())
 	cmd.PersistentFlags().StringVar(&flags.host, "host", "127.0.0.1", "Listening host")
 	cmd.PersistentFlags().StringVar(&flags.port, "port", "5173", "Listening port")
+	cmd.PersistentFlags().StringVar(&flags.listen, "listen", "", "Listening address as tcp://<host>:<port> or unix://<path> (overrides --host and --port)")
+	cmd.PersistentFlags().StringVar(&flags.socketMode, "socket-mode", "0600", "File permissions of the Unix domain socket (see --listen)")
+	cmd.PersistentFlags().StringVar(&flags.basePath, "base-path", "", "Serve the UI and the API under the path prefix (e.g., /tools/kexp)")
+	cmd.PersistentFlags().StringArrayVar(&flags.snapshots, "snapshot", nil, "Serve a snapshot file (offline mode, no cluster needed) - can be repeated")
+	cmd.PersistentFlags().StringVar(&flags.fakeClusterDir, "fake-cluster", "", "Serve a fake in-memory cluster seeded with the YAML fixtures from the directory (no cluster needed)")
+	cmd.PersistentFlags().BoolVar(&flags.inCluster, "in-cluster", false, "Use the pod's ServiceAccount to access the cluster kexp runs in (as the \""+kubeclient.InClusterContext+"\" context)")
+	cmd.PersistentFlags().StringVar(&flags.presetsDir, "presets-dir", "", "Directory with graph preset definitions (YAML)")
+	cmd.PersistentFlags().StringArrayVar(&flags.allowedOrigins, "allowed-origin", nil, "Allow cross-origin API requests from the origin (scheme://host[:port]) - can be repeated")
+	cmd.PersistentFlags().StringArrayVar(&flags.allowedHosts, "allowed-host", nil, "Accept requests for the host name (e.g., the one of a reverse proxy) in addition to the loopback and listening addresses - can be repeated")
+	cmd.PersistentFlags().StringArrayVar(&flags.trustedProxies, "trusted-proxy", nil, "Trust the X-Forwarded-For header (client addresses in the logs) set by the proxy with the IP or CIDR - can be repeated")
+	cmd.PersistentFlags().StringVar(&flags.authMode, "auth", "token", "Authentication: token (an access token or htpasswd users) or none")
+	cmd.PersistentFlags().StringVar(&flags.authTokenFile, "auth-token-file", "", "Read the access token from the file instead of generating one")
+	cmd.PersistentFlags().StringVar(&flags.authHtpasswd, "auth-htpasswd", "", "Authenticate users with an htpasswd file (bcrypt hashes only)")
+	cmd.PersistentFlags().StringVar(&flags.tlsCert, "tls-cert", "", "Serve HTTPS with the certificate (PEM) - requires --tls-key")
+	cmd.PersistentFlags().StringVar(&flags.tlsKey, "tls-key", "", "Private key (PEM) for --tls-cert")
+	cmd.PersistentFlags().BoolVar(&flags.tlsSelfSigned, "tls-self-signed", false, "Serve HTTPS with a self-signed certificate generated on start")
+	cmd.PersistentFlags().BoolVar(&flags.readOnly, "read-only", false, "Disable all actions changing the cluster (edits, deletions, etc.)")
+	cmd.PersistentFlags().StringVar(&flags.policyFile, "policy", "", "YAML file with rules allowing or denying actions per verb, context, resource, and namespace")
+	cmd.PersistentFlags().StringVar(&flags.auditLog, "audit-log", "", "Append every action changing the cluster (who, what, and how) to a JSONL file")
+	cmd.PersistentFlags().Float32Var(&flags.qps, "qps", kubeclient.DefaultRateLimit.QPS, "Max Kubernetes API requests per second per context (0 - no limit)")
+	cmd.PersistentFlags().IntVar(&flags.burst, "burst", kubeclient.DefaultRateLimit.Burst, "Max burst of Kubernetes API requests per context")
+	cmd.PersistentFlags().StringArrayVar(&flags.rateLimits, "rate-limit", nil, "Per-context rate limit as <context>=<qps>[:<burst>] - can be repeated")
+	cmd.Flags().StringVar(&flags.record, "record", "", "Append every watch event to a JSONL file (see 'kexp replay')")
+	cmd.Flags().BoolVar(&flags.recordSecretData, "record-secret-data", false, "Record Secrets' values as is (they are redacted by default)")
+
+	replayCmd := &cobra.Command{
+		Use:   "replay <file>",
+		Short: "Serve the UI with a fake cluster replaying recorded watch events",
+		Args:  cobra.ExactArgs(1),
+		Run:   replay(&flags),
+	}
+	replayCmd.Flags().Float64Var(&flags.replaySpeed, "speed", 1, "Replay speed (2 - twice as fast as recorded)")
+	replayCmd.Flags().DurationVar(&flags.replayMaxPause, "max-pause", 10*time.Second, "Max pause between two events (e.g., between recording sessions) - 0 means no limit")
+	replayCmd.Flags().BoolVar(&flags.replayLoop, "loop", false, "Start over when the recording is over")
+	cmd.AddCommand(replayCmd)
+
+	addHeadlessCommands(cmd, &flags)
 
 	if err := cmd.Execute(); err != nil {
 		logrus.WithError(err).Fatal("Command failed")
@@ -65,9 +169,21 @@ func main() {
 //go:embed ui/dist/*
 var uiStaticFS embed.FS
 
+// How long to wait for the in-flight requests and
+// the stream connections to finish on SIGTERM.
+const shutdownTimeout = 10 * time.Second
+
 func run(flags *flagpole) func(cmd *cobra.Command, args []string) {
 	return func(cmd *cobra.Command, args []string) {
-		kubeClientPool, err := initKubeClientPoolWithRetry(cmd.Context(), flags, 300*time.Second)
+		var (
+			kubeClientPool *kubeclient.ClientPool
+			err            error
+		)
+		if len(flags.snapshots) > 0 || flags.fakeClusterDir != "" {
+			kubeClientPool, err = initOfflineClientPool(flags.snapshots, flags.fakeClusterDir)
+		} else {
+			kubeClientPool, err = initKubeClientPoolWithRetry(cmd.Context(), flags, 300*time.Second)
+		}
 		if err != nil {
 			logrus.
 				WithError(err).
@@ -77,64 +193,480 @@ func run(flags *flagpole) func(cmd *cobra.Command, args []string) {
 			WithField("contexts", kubeClientPool.Contexts()).
 			Debug("Kube context discovery finished")
 
-		logrus.Infof("Starting server on %v:%v", flags.host, flags.port)
+		serve(flags, kubeClientPool)
+	}
+}
 
-		router := gin.New()
-		router.Use(gin.Logger())
-		router.Use(api.MiddlewareRequestID)
+func replay(flags *flagpole) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		events, err := readRecording(args[0])
+		if err != nil {
+			logrus.
+				WithError(err).
+				Fatal("Could not read recording")
+		}
 
-		kubeContextsHandler := restkubecontexts.NewHandler(
-			kubeClientPool,
-			logrus.NewEntry(logrus.StandardLogger()),
-		)
-		kubeContextsv1 := router.Group("/api/kube/v1/contexts")
-		kubeContextsv1.GET("/", kubeContextsHandler.List)
+		player, err := recording.NewPlayer(events, logrus.WithField("recording", args[0]))
+		if err != nil {
+			logrus.
+				WithError(err).
+				Fatal("Could not load recording")
+		}
 
-		kubeResourcesHandler := restkuberesources.NewHandler(
-			kubeClientPool,
-			logrus.NewEntry(logrus.StandardLogger()),
-		)
-		kubeResourcesv1 := router.Group("/api/kube/v1/contexts/:ctx/resources")
-		kubeResourcesv1.GET("/", kubeResourcesHandler.List)
+		kubeClientPool := kubeclient.NewPool()
+		for name, cluster := range player.Clusters() {
+			if err := kubeClientPool.AddStatic(name, "", player.ClusterUID(name), "", cluster.Clients()); err != nil {
+				logrus.
+					WithError(err).
+					Fatal("Could not initialize Kubernetes client pool")
+			}
+		}
 
-		kubeObjectsHandler := restkubeobjects.NewHandler(
-			kubeClientPool,
-			logrus.NewEntry(logrus.StandardLogger()),
-		)
-		kubeObjectsv1 := router.Group("/api/kube/v1/contexts/:ctx/resources")
-		kubeObjectsv1.GET("/:group/:version/:resource/", kubeObjectsHandler.List)
-		kubeObjectsv1.GET("/:group/:version/namespaces/:namespace/:resource/", kubeObjectsHandler.List)
-		kubeObjectsv1.GET("/:group/:version/:resource/:name/", kubeObjectsHandler.Get)
-		kubeObjectsv1.GET("/:group/:version/namespaces/:namespace/:resource/:name/", kubeObjectsHandler.Get)
-		kubeObjectsv1.PUT("/:group/:version/:resource/:name/", kubeObjectsHandler.Update)
-		kubeObjectsv1.PUT("/:group/:version/namespaces/:namespace/:resource/:name/", kubeObjectsHandler.Update)
-		kubeObjectsv1.DELETE("/:group/:version/:resource/:name/", kubeObjectsHandler.Delete)
-		kubeObjectsv1.DELETE("/:group/:version/namespaces/:namespace/:resource/:name/", kubeObjectsHandler.Delete)
-
-		rpcCallDispatcher := streamrpc.NewCallDispatcher()
-		rpcCallDispatcher.RegisterCallHandler(
-			streamkubeobjects.Watch,
-			streamkubeobjects.NewWatchHandler(kubeClientPool),
-		)
-		streamHandler := stream.NewHandler(logrus.NewEntry(logrus.StandardLogger()))
-		streamHandler.RegisterMessageHandler(streamrpc.MessageTypeCall, rpcCallDispatcher)
-		streamv1 := router.Group("/api/stream/v1")
-		streamv1.GET("/", streamHandler.Connect)
+		go func() {
+			if err := player.Play(context.Background(), flags.replaySpeed, flags.replayMaxPause, flags.replayLoop); err != nil {
+				logrus.
+					WithError(err).
+					Fatal("Replay failed")
+			}
+		}()
+
+		serve(flags, kubeClientPool)
+	}
+}
+
+func serve(flags *flagpole, kubeClientPool *kubeclient.ClientPool) {
+	if err := parseListen(flags); err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Could not parse listening address")
+	}
+
+	presetRegistry, err := presets.NewRegistry(flags.presetsDir, logrus.NewEntry(logrus.StandardLogger()))
+	if err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Could not load graph presets")
+	}
+
+	pol := &policy.Policy{Rules: []policy.Rule{}}
+	if flags.policyFile != "" {
+		pol, err = policy.Load(flags.policyFile)
+		if err != nil {
+			logrus.
+				WithError(err).
+				Fatal("Could not load policy")
+		}
+	}
+	if flags.readOnly {
+		pol.ReadOnly = true
+		logrus.Info("Read-only mode - all actions changing the cluster are disabled")
+	}
+
+	auditLog, err := audit.Open(flags.auditLog)
+	if err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Could not open audit log")
+	}
+	defer auditLog.Close()
 
-		if uiStaticFS, err := fs.Sub(uiStaticFS, "ui/dist"); err != nil {
-			logrus.WithError(err).Fatal("Could not load static files")
+	tlsConfig, err := initTLS(flags)
+	if err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Could not initialize TLS")
+	}
+
+	listener, err := listen(flags)
+	if err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Could not listen")
+	}
+
+	logrus.Infof("Starting server on %v", flags.address())
+
+	csrf, err := api.NewCSRFProtection(
+		flags.allowedOrigins,
+		append(certs.Hosts(flags.listenHost()), flags.allowedHosts...),
+	)
+	if err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Could not initialize CSRF protection")
+	}
+
+	basePath := api.NormalizeBasePath(flags.basePath)
+
+	authn, err := initAuth(flags, basePath)
+	if err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Could not initialize authentication")
+	}
+
+	router := gin.New()
+	// Without trusted proxies, the client address can't be spoofed
+	// (with an X-Forwarded-For header) in the logs and the audit log.
+	if err := router.SetTrustedProxies(flags.trustedProxies); err != nil {
+		logrus.
+			WithError(err).
+			Fatal("Invalid --trusted-proxy")
+	}
+	router.Use(gin.Logger())
+	router.Use(api.MiddlewareRequestID)
+	router.Use(api.MiddlewareBasePath(basePath))
+	router.Use(csrf.Middleware)
+	if authn != nil {
+		router.Use(authn.Middleware)
+	}
+	router.Use(api.MiddlewareImpersonation)
+	router.Use(api.MiddlewarePriority)
+
+	// All routes are served under the base path.
+	root := router.Group(basePath)
+
+	if authn != nil {
+		root.GET(auth.PathLogin, authn.LoginPage)
+		root.POST(auth.PathLogin, authn.Login)
+		root.POST(auth.PathLogout, authn.Logout)
+	}
+
+	configHandler := restconfig.NewHandler(
+		version,
+		pol,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	root.GET("/api/config", configHandler.Get)
+
+	auditHandler := restaudit.NewHandler(
+		auditLog,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	root.GET("/api/audit", auditHandler.List)
+
+	kubeContextsHandler := restkubecontexts.NewHandler(
+		kubeClientPool,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubeContextsv1 := root.Group("/api/kube/v1/contexts")
+	kubeContextsv1.GET("/", kubeContextsHandler.List)
+
+	// All per-context routes go under this group, so the read-only
+	// guard covers every (future) route changing something.
+	kubeContextv1 := root.Group("/api/kube/v1/contexts/:ctx")
+	kubeContextv1.Use(api.MiddlewareReadOnly(
+		pol,
+		// Snapshots only read the objects.
+		api.Route{Method: http.MethodPost, Path: kubeContextv1.BasePath() + "/snapshots/"},
+	))
+
+	kubeResourcesHandler := restkuberesources.NewHandler(
+		kubeClientPool,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubeResourcesv1 := kubeContextv1.Group("/resources")
+	kubeResourcesv1.GET("/", kubeResourcesHandler.List)
+
+	kubeObjectsHandler := restkubeobjects.NewHandler(
+		kubeClientPool,
+		auditLog,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubeObjectsv1 := kubeContextv1.Group("/resources")
+	kubeObjectsv1.GET("/:group/:version/:resource/", kubeObjectsHandler.List)
+	kubeObjectsv1.GET("/:group/:version/namespaces/:namespace/:resource/", kubeObjectsHandler.List)
+	kubeObjectsv1.GET("/:group/:version/:resource/:name/", kubeObjectsHandler.Get)
+	kubeObjectsv1.GET("/:group/:version/namespaces/:namespace/:resource/:name/", kubeObjectsHandler.Get)
+	kubeObjectsv1.PUT("/:group/:version/:resource/:name/", api.MiddlewarePolicy(pol, policy.VerbUpdate), kubeObjectsHandler.Update)
+	kubeObjectsv1.PUT("/:group/:version/namespaces/:namespace/:resource/:name/", api.MiddlewarePolicy(pol, policy.VerbUpdate), kubeObjectsHandler.Update)
+	kubeObjectsv1.DELETE("/:group/:version/:resource/:name/", api.MiddlewarePolicy(pol, policy.VerbDelete), kubeObjectsHandler.Delete)
+	kubeObjectsv1.DELETE("/:group/:version/namespaces/:namespace/:resource/:name/", api.MiddlewarePolicy(pol, policy.VerbDelete), kubeObjectsHandler.Delete)
+
+	kubePermissionsHandler := restkubepermissions.NewHandler(
+		kubeClientPool,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubePermissionsv1 := kubeContextv1.Group("/permissions")
+	kubePermissionsv1.GET("/", kubePermissionsHandler.List)
+
+	kubeSchemasHandler := restkubeschemas.NewHandler(
+		kubeClientPool,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubeSchemasv1 := kubeContextv1.Group("/schemas")
+	kubeSchemasv1.GET("/:group/:version/:kind/", kubeSchemasHandler.Get)
+
+	kubeRelationsHandler := restkuberelations.NewHandler(
+		kubeClientPool,
+		presetRegistry,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubeRelationsv1 := kubeContextv1.Group("/relations")
+	kubeRelationsv1.GET("/:group/:version/:resource/:name/", kubeRelationsHandler.Get)
+	kubeRelationsv1.GET("/:group/:version/namespaces/:namespace/:resource/:name/", kubeRelationsHandler.Get)
+
+	kubeExportHandler := restkubeexport.NewHandler(
+		kubeClientPool,
+		presetRegistry,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubeExportv1 := kubeContextv1.Group("/export")
+	kubeExportv1.GET("/relations/:group/:version/:resource/:name/", kubeExportHandler.Relations)
+	kubeExportv1.GET("/relations/:group/:version/namespaces/:namespace/:resource/:name/", kubeExportHandler.Relations)
+	kubeExportv1.GET("/resources/:group/:version/:resource/", kubeExportHandler.Resources)
+	kubeExportv1.GET("/resources/:group/:version/namespaces/:namespace/:resource/", kubeExportHandler.Resources)
+
+	kubeDiffHandler := restkubediff.NewHandler(
+		kubeClientPool,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubeDiffv1 := kubeContextv1.Group("/diff")
+	kubeDiffv1.GET("/:group/:version/:resource/", kubeDiffHandler.Get)
+	kubeDiffv1.GET("/:group/:version/namespaces/:namespace/:resource/", kubeDiffHandler.Get)
+	kubeDiffv1.GET("/:group/:version/:resource/:name/", kubeDiffHandler.Get)
+	kubeDiffv1.GET("/:group/:version/namespaces/:namespace/:resource/:name/", kubeDiffHandler.Get)
+
+	kubeSnapshotsHandler := restkubesnapshots.NewHandler(
+		kubeClientPool,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubeSnapshotsv1 := kubeContextv1.Group("/snapshots")
+	kubeSnapshotsv1.POST("/", kubeSnapshotsHandler.Create)
+
+	kubePresetsHandler := restkubepresets.NewHandler(
+		kubeClientPool,
+		presetRegistry,
+		logrus.NewEntry(logrus.StandardLogger()),
+	)
+	kubePresetsv1 := kubeContextv1.Group("/presets")
+	kubePresetsv1.GET("/", kubePresetsHandler.List)
+
+	var recorder *recording.Recorder
+	if flags.record != "" {
+		recorder, err = recording.NewRecorder(flags.record, flags.recordSecretData)
+		if err != nil {
+			logrus.
+				WithError(err).
+				Fatal("Could not start recording")
+		}
+		defer recorder.Close()
+
+		logrus.Infof("Recording watch events to %s", flags.record)
+	}
+
+	rpcCallDispatcher := streamrpc.NewCallDispatcher()
+	rpcCallDispatcher.RegisterCallHandler(
+		streamkubeobjects.Watch,
+		streamkubeobjects.NewWatchHandler(kubeClientPool, recorder),
+	)
+	rpcCallDispatcher.RegisterCallHandler(
+		streamkubeobjects.WatchAggregated,
+		streamkubeobjects.NewWatchAggregatedHandler(kubeClientPool, recorder),
+	)
+	rpcCallDispatcher.RegisterCallHandler(
+		streamkubeobjects.WatchRelated,
+		streamkubeobjects.NewWatchRelatedHandler(kubeClientPool, presetRegistry),
+	)
+	streamHandler := stream.NewHandler(csrf.CheckOrigin, logrus.NewEntry(logrus.StandardLogger()))
+	streamHandler.RegisterMessageHandler(streamrpc.MessageTypeCall, rpcCallDispatcher)
+	streamv1 := root.Group("/api/stream/v1")
+	streamv1.GET("/", streamHandler.Connect)
+
+	if uiStaticFS, err := fs.Sub(uiStaticFS, "ui/dist"); err != nil {
+		logrus.WithError(err).Fatal("Could not load static files")
+	} else {
+		uiHandler := api.NewUIHandler(uiStaticFS)
+		root.GET("/ui/*filepath", uiHandler.Serve)
+
+		root.GET("/", func(c *gin.Context) {
+			c.Redirect(http.StatusMovedPermanently, api.BasePath(c)+"/ui/")
+		})
+	}
+
+	server := &http.Server{
+		Handler:   router.Handler(),
+		TLSConfig: tlsConfig,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			served <- server.ServeTLS(listener, "", "")
 		} else {
-			router.StaticFS("/ui", http.FS(uiStaticFS))
+			served <- server.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-served:
+		logrus.WithError(err).Fatal("Router failed")
+	case <-ctx.Done():
+	}
+
+	// A second signal kills the process right away.
+	stop()
 
-			router.GET("/", func(c *gin.Context) {
-				c.Redirect(http.StatusMovedPermanently, "/ui")
-			})
+	logrus.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stops listening (removes the Unix socket) and waits for the
+	// in-flight requests. WebSocket connections aren't tracked by
+	// the server - the stream handler drains them.
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logrus.WithError(err).Warn("Couldn't shut down the server gracefully")
+	}
+
+	streamHandler.GoAway()
+
+	if err := rpcCallDispatcher.Shutdown(shutdownCtx); err != nil {
+		logrus.WithError(err).Warn("Couldn't stop the active RPC calls gracefully")
+	}
+
+	if err := streamHandler.Shutdown(shutdownCtx); err != nil {
+		logrus.WithError(err).Warn("Couldn't close the stream connections gracefully")
+	}
+
+	kubeClientPool.Close()
+
+	logrus.Info("Server stopped")
+}
+
+// listenHost is the host the clients connect to (as far as kexp can tell).
+func (f *flagpole) listenHost() string {
+	if f.socketPath != "" {
+		return "localhost" // Other names (e.g., the proxy's) need --allowed-host.
+	}
+	return f.host
+}
+
+func (f *flagpole) scheme() string {
+	if f.tlsCert != "" || f.tlsSelfSigned {
+		return "https"
+	}
+	return "http"
+}
+
+// initTLS returns nil if the server should speak plain HTTP.
+func initTLS(flags *flagpole) (*tls.Config, error) {
+	if (flags.tlsCert == "") != (flags.tlsKey == "") {
+		return nil, fmt.Errorf("--tls-cert and --tls-key must be used together")
+	}
+	if flags.tlsCert != "" && flags.tlsSelfSigned {
+		return nil, fmt.Errorf("--tls-cert and --tls-self-signed are mutually exclusive")
+	}
+
+	var (
+		cert tls.Certificate
+		err  error
+	)
+	switch {
+	case flags.tlsCert != "":
+		cert, err = certs.Load(flags.tlsCert, flags.tlsKey)
+
+	case flags.tlsSelfSigned:
+		cert, err = certs.SelfSigned(certs.Hosts(flags.listenHost()))
+
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.
+		WithField("fingerprint", certs.Fingerprint(cert)).
+		Info("Serving HTTPS")
+	if flags.tlsSelfSigned {
+		// Printed out of the logs for the users to compare
+		// with what the browser shows before trusting it.
+		fmt.Fprintf(
+			os.Stderr,
+			"\n    Self-signed certificate SHA-256 fingerprint:\n\n        %s\n\n",
+			certs.Fingerprint(cert),
+		)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// initAuth returns nil if the authentication is off.
+func initAuth(flags *flagpole, basePath string) (*auth.Auth, error) {
+	// Loopback isn't safe either - other local users and (rebinding)
+	// web pages can reach it, hence the token by default everywhere.
+	mode := flags.authMode
+	switch mode {
+	case "token", "none":
+
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q - expected token or none", mode)
+	}
+
+	if mode == "none" {
+		if flags.authTokenFile != "" || flags.authHtpasswd != "" {
+			return nil, fmt.Errorf("--auth-token-file and --auth-htpasswd can't be used with --auth none")
+		}
+
+		logrus.Warn("Authentication is off - anyone who can reach the server gets access to your clusters")
+		return nil, nil
+	}
+
+	if flags.authTokenFile != "" && flags.authHtpasswd != "" {
+		return nil, fmt.Errorf("--auth-token-file and --auth-htpasswd are mutually exclusive")
+	}
+
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	if flags.authHtpasswd != "" {
+		verifier, err := auth.LoadHtpasswd(flags.authHtpasswd)
+		if err != nil {
+			return nil, err
 		}
 
-		if err := router.Run(flags.host + ":" + flags.port); err != nil {
-			logrus.WithError(err).Fatal("Router failed")
+		logrus.Infof("Authenticating users from %s", flags.authHtpasswd)
+		return auth.New(verifier, basePath, logger), nil
+	}
+
+	if flags.authTokenFile != "" {
+		token, err := auth.ReadTokenFile(flags.authTokenFile)
+		if err != nil {
+			return nil, err
 		}
+
+		logrus.Infof("Using the access token from %s", flags.authTokenFile)
+		return auth.New(auth.NewTokenVerifier(token), basePath, logger), nil
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return nil, err
 	}
+
+	if flags.socketPath != "" {
+		// No URL to give - the socket is meant to be exposed by a proxy.
+		fmt.Fprintf(os.Stderr, "\n    k'exp access token:\n\n        %s\n\n", token)
+		return auth.New(auth.NewTokenVerifier(token), basePath, logger), nil
+	}
+
+	host := flags.host
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	fmt.Fprintf(
+		os.Stderr,
+		"\n    To access k'exp, open this URL in a browser:\n\n        %s://%s%s/?%s=%s\n\n",
+		flags.scheme(), net.JoinHostPort(host, flags.port), basePath, auth.QueryToken, token,
+	)
+
+	return auth.New(auth.NewTokenVerifier(token), basePath, logger), nil
 }
 
 func initKubeClientPool(ctx context.Context, flags *flagpole) (*kubeclient.ClientPool, error) {
@@ -148,7 +680,27 @@ func initKubeClientPool(ctx context.Context, flags *flagpole) (*kubeclient.Clien
 		curKubeCtx = rawConfig.CurrentContext
 	}
 
+	rateLimits, err := parseRateLimits(flags.rateLimits, flags.burst)
+	if err != nil {
+		return nil, err
+	}
+
 	pool := kubeclient.NewPool()
+	pool.SetRateLimits(kubeclient.RateLimit{QPS: flags.qps, Burst: flags.burst}, rateLimits)
+
+	if flags.inCluster {
+		if err := pool.AddInCluster(ctx); err != nil {
+			logrus.
+				WithField("context", kubeclient.InClusterContext).
+				WithError(err).
+				Warnf("couldn't add a context to the pool")
+		} else if *flags.Context == "" {
+			// Pods rarely have a kubeconfig, but if there is one,
+			// the cluster kexp runs in still comes first.
+			curKubeCtx = kubeclient.InClusterContext
+		}
+	}
+
 	for name, kctx := range rawConfig.Contexts {
 		// TODO: This might be an overkill.
 		// TODO: Restore the original flags.Context value.
@@ -190,6 +742,41 @@ func initKubeClientPool(ctx context.Context, flags *flagpole) (*kubeclient.Clien
 	return pool, nil
 }
 
+// parseRateLimits parses <context>=<qps>[:<burst>] entries.
+// The bursts (including the --burst default) must be positive -
+// with a zero one, the rate limiter would never allow a request.
+func parseRateLimits(entries []string, defaultBurst int) (map[string]kubeclient.RateLimit, error) {
+	if defaultBurst < 1 {
+		return nil, fmt.Errorf("invalid --burst %d - must be at least 1", defaultBurst)
+	}
+
+	limits := map[string]kubeclient.RateLimit{}
+	for _, entry := range entries {
+		name, spec, found := strings.Cut(entry, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid rate limit %q - expected <context>=<qps>[:<burst>]", entry)
+		}
+
+		qpsStr, burstStr, hasBurst := strings.Cut(spec, ":")
+		qps, err := strconv.ParseFloat(qpsStr, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit %q - bad QPS: %w", entry, err)
+		}
+
+		limit := kubeclient.RateLimit{QPS: float32(qps), Burst: defaultBurst}
+		if hasBurst {
+			if limit.Burst, err = strconv.Atoi(burstStr); err != nil {
+				return nil, fmt.Errorf("invalid rate limit %q - bad burst: %w", entry, err)
+			}
+			if limit.Burst < 1 {
+				return nil, fmt.Errorf("invalid rate limit %q - burst must be at least 1", entry)
+			}
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
 func initKubeClientPoolWithRetry(
 	ctx context.Context,
 	flags *flagpole,
@@ -208,3 +795,98 @@ func initKubeClientPoolWithRetry(
 
 	return nil, err
 }
+
+// initOfflineClientPool serves every snapshot as a separate context
+// (named after the snapshotted context) and the fake cluster (if any)
+// as the "fake" context.
+func initOfflineClientPool(paths []string, fakeClusterDir string) (*kubeclient.ClientPool, error) {
+	pool := kubeclient.NewPool()
+
+	if fakeClusterDir != "" {
+		objs, err := offline.LoadFixtures(fakeClusterDir)
+		if err != nil {
+			return nil, err
+		}
+
+		cluster, err := offline.NewCluster(nil, offline.ResourcesOf(objs, nil), objs)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load fake cluster %s: %w", fakeClusterDir, err)
+		}
+
+		if err := pool.AddStatic("fake", "fake", offline.ClusterUIDOf(objs), "default", cluster.Clients()); err != nil {
+			return nil, err
+		}
+
+		logrus.
+			WithField("context", "fake").
+			WithField("fixtures", fakeClusterDir).
+			WithField("objects", len(objs)).
+			Info("Serving fake cluster")
+	}
+
+	for _, path := range paths {
+		snap, err := readSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+
+		cluster, err := offline.NewCluster(snap.Manifest.Groups, snap.Manifest.Resources, snap.Objects)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load snapshot %s: %w", path, err)
+		}
+
+		name := snap.Manifest.Context
+		if name == "" {
+			name = filepath.Base(path)
+		}
+		if _, err := pool.Context(name); err == nil {
+			name = name + " (" + filepath.Base(path) + ")"
+		}
+
+		if err := pool.AddStatic(
+			name,
+			snap.Manifest.Cluster,
+			snap.Manifest.ClusterUID,
+			"",
+			cluster.Clients(),
+		); err != nil {
+			return nil, err
+		}
+
+		logrus.
+			WithField("context", name).
+			WithField("snapshot", path).
+			WithField("objects", len(snap.Objects)).
+			Info("Serving snapshot")
+	}
+
+	return pool, nil
+}
+
+func readSnapshot(path string) (*snapshot.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snap, err := snapshot.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read snapshot %s: %w", path, err)
+	}
+	return snap, nil
+}
+
+func readRecording(path string) ([]recording.Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events, err := recording.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read recording %s: %w", path, err)
+	}
+	return events, nil
+}
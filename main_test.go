@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		entries      []string
+		defaultBurst int
+		want         map[string]kubeclient.RateLimit
+		wantErr      bool
+	}{
+		{
+			name:         "qps only",
+			entries:      []string{"prod=2.5"},
+			defaultBurst: 10,
+			want:         map[string]kubeclient.RateLimit{"prod": {QPS: 2.5, Burst: 10}},
+		},
+		{
+			name:         "qps and burst",
+			entries:      []string{"prod=1:3", "dev=0"},
+			defaultBurst: 10,
+			want: map[string]kubeclient.RateLimit{
+				"prod": {QPS: 1, Burst: 3},
+				"dev":  {QPS: 0, Burst: 10},
+			},
+		},
+		{"no context", []string{"=1"}, 10, nil, true},
+		{"bad qps", []string{"prod=fast"}, 10, nil, true},
+		{"bad burst", []string{"prod=1:many"}, 10, nil, true},
+		{"zero burst", []string{"prod=1:0"}, 10, nil, true},
+		{"zero default burst", nil, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRateLimits(tt.entries, tt.defaultBurst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRateLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRateLimits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package offline serves Kubernetes objects from memory, so kexp can
+// be used with no cluster connected (e.g., to explore a snapshot).
+package offline
+
+import (
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+// Cluster is an in-memory stand-in for a Kubernetes API server.
+// Writes (through the clients or Apply/Delete) go to memory.
+// Everything is allowed (there is no one to ask).
+type Cluster struct {
+	clients kubeclient.Clients
+
+	dynamicTracker kubetesting.ObjectTracker
+	typedTracker   kubetesting.ObjectTracker
+
+	kindToResource map[schema.GroupVersionKind]schema.GroupVersionResource
+}
+
+// NewCluster returns a cluster serving the discovery data and the objects.
+// The groups are optional - they can be derived from the resource lists.
+func NewCluster(
+	groups []metav1.APIGroup,
+	resources []*metav1.APIResourceList,
+	objs []*unstructured.Unstructured,
+) (*Cluster, error) {
+	listKinds := map[schema.GroupVersionResource]string{}
+	kindToResource := map[schema.GroupVersionKind]schema.GroupVersionResource{}
+
+	for _, list := range resources {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group version %q: %w", list.GroupVersion, err)
+		}
+
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") {
+				continue // Subresource.
+			}
+
+			gvr := gv.WithResource(res.Name)
+			listKinds[gvr] = res.Kind + "List"
+			kindToResource[gv.WithKind(res.Kind)] = gvr
+		}
+	}
+
+	fakeDynamic := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	clientset := fakekubernetes.NewSimpleClientset()
+
+	clientset.PrependReactor("create", "selfsubjectrulesreviews", allowAllRules)
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", allowAllAccess)
+
+	c := &Cluster{
+		clients: kubeclient.Clients{
+			Discovery: memory.NewMemCacheClient(newStaticDiscovery(groups, resources)),
+			Dynamic: &dynamicClient{
+				FakeDynamicClient: fakeDynamic,
+				listable:          listable(listKinds),
+			},
+			Clientset: clientset,
+		},
+		dynamicTracker: fakeDynamic.Tracker(),
+		typedTracker:   clientset.Tracker(),
+		kindToResource: kindToResource,
+	}
+
+	for _, obj := range objs {
+		if err := c.Apply(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Cluster) Clients() kubeclient.Clients {
+	return c.clients
+}
+
+// Apply creates the object or replaces its current version.
+func (c *Cluster) Apply(obj *unstructured.Unstructured) error {
+	gvr := c.resourceFor(obj.GroupVersionKind())
+
+	err := c.dynamicTracker.Create(gvr, obj, obj.GetNamespace())
+	if apierrors.IsAlreadyExists(err) {
+		err = c.dynamicTracker.Update(gvr, obj, obj.GetNamespace())
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't apply object %s %s/%s: %w",
+			obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	// Some APIs (e.g., presets) use typed clients.
+	if typed, err := scheme.Scheme.New(obj.GroupVersionKind()); err == nil {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed); err == nil {
+			err := c.typedTracker.Create(gvr, typed, obj.GetNamespace())
+			if apierrors.IsAlreadyExists(err) {
+				_ = c.typedTracker.Update(gvr, typed, obj.GetNamespace())
+			}
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the object (if it exists).
+func (c *Cluster) Delete(obj *unstructured.Unstructured) error {
+	gvr := c.resourceFor(obj.GroupVersionKind())
+
+	err := c.dynamicTracker.Delete(gvr, obj.GetNamespace(), obj.GetName())
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	_ = c.typedTracker.Delete(gvr, obj.GetNamespace(), obj.GetName())
+	return nil
+}
+
+func (c *Cluster) resourceFor(gvk schema.GroupVersionKind) schema.GroupVersionResource {
+	if gvr, found := c.kindToResource[gvk]; found {
+		return gvr
+	}
+
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return gvr
+}
+
+func listable(listKinds map[schema.GroupVersionResource]string) map[schema.GroupVersionResource]bool {
+	res := map[schema.GroupVersionResource]bool{}
+	for gvr := range listKinds {
+		res[gvr] = true
+	}
+	return res
+}
+
+func allowAllRules(action kubetesting.Action) (bool, runtime.Object, error) {
+	review := action.(kubetesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectRulesReview).DeepCopy()
+	review.Status = authorizationv1.SubjectRulesReviewStatus{
+		ResourceRules: []authorizationv1.ResourceRule{{
+			Verbs:     []string{"*"},
+			APIGroups: []string{"*"},
+			Resources: []string{"*"},
+		}},
+		NonResourceRules: []authorizationv1.NonResourceRule{{
+			Verbs:           []string{"*"},
+			NonResourceURLs: []string{"*"},
+		}},
+	}
+	return true, review, nil
+}
+
+func allowAllAccess(action kubetesting.Action) (bool, runtime.Object, error) {
+	review := action.(kubetesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview).DeepCopy()
+	review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true}
+	return true, review, nil
+}
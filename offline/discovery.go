@@ -0,0 +1,155 @@
+package offline
+
+import (
+	"errors"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/openapi"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+var errNoOpenAPI = errors.New("OpenAPI schemas aren't available offline")
+
+// staticDiscovery serves recorded discovery data. Unlike the client-go's
+// fake, it keeps the recorded preferred versions and doesn't panic
+// on OpenAPI requests.
+type staticDiscovery struct {
+	*fakediscovery.FakeDiscovery
+
+	groups []metav1.APIGroup
+}
+
+var _ discovery.DiscoveryInterface = (*staticDiscovery)(nil)
+
+func newStaticDiscovery(groups []metav1.APIGroup, resources []*metav1.APIResourceList) *staticDiscovery {
+	if len(groups) == 0 {
+		groups = groupsOf(resources)
+	}
+
+	return &staticDiscovery{
+		FakeDiscovery: &fakediscovery.FakeDiscovery{
+			Fake: &kubetesting.Fake{Resources: resources},
+		},
+		groups: groups,
+	}
+}
+
+func (d *staticDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	return &metav1.APIGroupList{Groups: d.groups}, nil
+}
+
+func (d *staticDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	groups := []*metav1.APIGroup{}
+	for i := range d.groups {
+		groups = append(groups, &d.groups[i])
+	}
+	return groups, d.Resources, nil
+}
+
+func (d *staticDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	return discovery.ServerPreferredResources(d)
+}
+
+func (d *staticDiscovery) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	return discovery.ServerPreferredNamespacedResources(d)
+}
+
+func (d *staticDiscovery) OpenAPIV3() openapi.Client {
+	return noOpenAPI{}
+}
+
+func (d *staticDiscovery) WithLegacy() discovery.DiscoveryInterface {
+	return d
+}
+
+type noOpenAPI struct{}
+
+func (noOpenAPI) Paths() (map[string]openapi.GroupVersion, error) {
+	return nil, errNoOpenAPI
+}
+
+// groupsOf derives the API groups from the resource lists. The first
+// version of a group becomes its preferred version.
+func groupsOf(resources []*metav1.APIResourceList) []metav1.APIGroup {
+	var groups []metav1.APIGroup
+	index := map[string]int{}
+
+	for _, list := range resources {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		version := metav1.GroupVersionForDiscovery{
+			GroupVersion: list.GroupVersion,
+			Version:      gv.Version,
+		}
+
+		i, found := index[gv.Group]
+		if !found {
+			i = len(groups)
+			index[gv.Group] = i
+			groups = append(groups, metav1.APIGroup{
+				Name:             gv.Group,
+				PreferredVersion: version,
+			})
+		}
+		groups[i].Versions = append(groups[i].Versions, version)
+	}
+
+	return groups
+}
+
+// ResourcesOf derives discovery data from the objects themselves - for
+// sources that don't come with it (e.g., fixtures). Resource names are
+// guessed from kinds unless the names map has them.
+func ResourcesOf(
+	objs []*unstructured.Unstructured,
+	names map[schema.GroupVersionKind]string,
+) []*metav1.APIResourceList {
+	lists := map[string]*metav1.APIResourceList{}
+	seen := map[schema.GroupVersionKind]bool{}
+
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if seen[gvk] {
+			continue
+		}
+		seen[gvk] = true
+
+		name := names[gvk]
+		if name == "" {
+			gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+			name = gvr.Resource
+		}
+
+		gv := gvk.GroupVersion().String()
+		if lists[gv] == nil {
+			lists[gv] = &metav1.APIResourceList{GroupVersion: gv}
+		}
+		lists[gv].APIResources = append(lists[gv].APIResources, metav1.APIResource{
+			Name:       name,
+			Kind:       gvk.Kind,
+			Namespaced: obj.GetNamespace() != "",
+			Verbs:      []string{"create", "delete", "get", "list", "patch", "update", "watch"},
+		})
+	}
+
+	res := []*metav1.APIResourceList{}
+	for _, list := range lists {
+		sort.Slice(list.APIResources, func(i, j int) bool {
+			return list.APIResources[i].Name < list.APIResources[j].Name
+		})
+		res = append(res, list)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].GroupVersion < res[j].GroupVersion
+	})
+	return res
+}
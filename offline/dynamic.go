@@ -0,0 +1,125 @@
+package offline
+
+import (
+	"context"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+)
+
+// dynamicClient wraps the client-go's fake that panics on listing unknown
+// resources and ignores field selectors (kexp relies on metadata.name
+// and metadata.namespace selectors).
+type dynamicClient struct {
+	*fakedynamic.FakeDynamicClient
+
+	listable map[schema.GroupVersionResource]bool
+}
+
+var _ dynamic.Interface = (*dynamicClient)(nil)
+
+func (c *dynamicClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	base := c.FakeDynamicClient.Resource(gvr)
+
+	return &namespaceableResourceClient{
+		resourceClient: resourceClient{
+			ResourceInterface: base,
+			gvr:               gvr,
+			listable:          c.listable[gvr],
+		},
+		base: base,
+	}
+}
+
+type namespaceableResourceClient struct {
+	resourceClient
+
+	base dynamic.NamespaceableResourceInterface
+}
+
+func (c *namespaceableResourceClient) Namespace(ns string) dynamic.ResourceInterface {
+	return &resourceClient{
+		ResourceInterface: c.base.Namespace(ns),
+		gvr:               c.gvr,
+		listable:          c.listable,
+	}
+}
+
+type resourceClient struct {
+	dynamic.ResourceInterface
+
+	gvr      schema.GroupVersionResource
+	listable bool
+}
+
+func (c *resourceClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if !c.listable {
+		return nil, apierrors.NewNotFound(c.gvr.GroupResource(), "")
+	}
+
+	selector, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	// The fake applies the label selector but ignores the limit.
+	list, err := c.ResourceInterface.List(ctx, metav1.ListOptions{LabelSelector: opts.LabelSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	items := []unstructured.Unstructured{}
+	for _, item := range list.Items {
+		if selector.Matches(objectFields(selector, &item)) {
+			items = append(items, item)
+		}
+	}
+	list.Items = items
+
+	return list, nil
+}
+
+func (c *resourceClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	fieldSelector, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	labelSelector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	w, err := c.ResourceInterface.Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		un, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			return event, true
+		}
+		return event, fieldSelector.Matches(objectFields(fieldSelector, un)) &&
+			labelSelector.Matches(labels.Set(un.GetLabels()))
+	}), nil
+}
+
+// objectFields resolves the fields the selector refers to. Unlike the real
+// API server, any string field can be selected on.
+func objectFields(selector fields.Selector, obj *unstructured.Unstructured) fields.Set {
+	set := fields.Set{}
+	for _, req := range selector.Requirements() {
+		value, _, _ := unstructured.NestedString(obj.Object, strings.Split(req.Field, ".")...)
+		set[req.Field] = value
+	}
+	return set
+}
@@ -0,0 +1,350 @@
+package offline_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	restkubecontexts "github.com/iximiuz/kexp/api/rest/kube/contexts"
+	restkubeobjects "github.com/iximiuz/kexp/api/rest/kube/objects"
+	restkuberelations "github.com/iximiuz/kexp/api/rest/kube/relations"
+	restkuberesources "github.com/iximiuz/kexp/api/rest/kube/resources"
+	"github.com/iximiuz/kexp/api/stream"
+	streamrpc "github.com/iximiuz/kexp/api/stream/rpc"
+	streamkubeobjects "github.com/iximiuz/kexp/api/stream/rpc/kube/objects"
+	"github.com/iximiuz/kexp/audit"
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/offline"
+	"github.com/iximiuz/kexp/presets"
+)
+
+const fixturesDir = "testdata/cluster"
+
+// newTestServer serves the REST API and the stream (the way main does)
+// backed by the fake cluster seeded with the test fixtures.
+func newTestServer(t *testing.T) (*httptest.Server, *audit.Log) {
+	t.Helper()
+
+	objs, err := offline.LoadFixtures(fixturesDir)
+	if err != nil {
+		t.Fatalf("LoadFixtures() failed: %v", err)
+	}
+
+	cluster, err := offline.NewCluster(nil, offline.ResourcesOf(objs, nil), objs)
+	if err != nil {
+		t.Fatalf("NewCluster() failed: %v", err)
+	}
+
+	pool := kubeclient.NewPool()
+	if err := pool.AddStatic("fake", "fake", offline.ClusterUIDOf(objs), "default", cluster.Clients()); err != nil {
+		t.Fatalf("AddStatic() failed: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	auditLog, err := audit.Open("")
+	if err != nil {
+		t.Fatalf("audit.Open() failed: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	entry := logrus.NewEntry(logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	contexts := restkubecontexts.NewHandler(pool, entry)
+	router.GET("/api/kube/v1/contexts/", contexts.List)
+
+	resources := restkuberesources.NewHandler(pool, entry)
+	router.GET("/api/kube/v1/contexts/:ctx/resources/", resources.List)
+
+	objects := restkubeobjects.NewHandler(pool, auditLog, entry)
+	objectsv1 := router.Group("/api/kube/v1/contexts/:ctx/resources")
+	objectsv1.GET("/:group/:version/:resource/", objects.List)
+	objectsv1.GET("/:group/:version/namespaces/:namespace/:resource/", objects.List)
+	objectsv1.GET("/:group/:version/namespaces/:namespace/:resource/:name/", objects.Get)
+	objectsv1.PUT("/:group/:version/namespaces/:namespace/:resource/:name/", objects.Update)
+	objectsv1.DELETE("/:group/:version/namespaces/:namespace/:resource/:name/", objects.Delete)
+
+	registry, err := presets.NewRegistry("", entry)
+	if err != nil {
+		t.Fatalf("presets.NewRegistry() failed: %v", err)
+	}
+	relations := restkuberelations.NewHandler(pool, registry, entry)
+	router.GET("/api/kube/v1/contexts/:ctx/relations/:group/:version/namespaces/:namespace/:resource/:name/", relations.Get)
+
+	dispatcher := streamrpc.NewCallDispatcher()
+	dispatcher.RegisterCallHandler(streamkubeobjects.Watch, streamkubeobjects.NewWatchHandler(pool, nil))
+
+	streamHandler := stream.NewHandler(func(*http.Request) bool { return true }, entry)
+	streamHandler.RegisterMessageHandler(streamrpc.MessageTypeCall, dispatcher)
+	router.GET("/api/stream/v1/", streamHandler.Connect)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server, auditLog
+}
+
+func doRequest(t *testing.T, method, url string, body string, out interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: couldn't decode response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestREST(t *testing.T) {
+	server, _ := newTestServer(t)
+	api := server.URL + "/api/kube/v1/contexts/"
+
+	var contexts []struct {
+		Name       string `json:"name"`
+		ClusterUID string `json:"clusterUID"`
+		Current    bool   `json:"current"`
+	}
+	if status := doRequest(t, http.MethodGet, api, "", &contexts); status != http.StatusOK {
+		t.Fatalf("list contexts: status = %d", status)
+	}
+	if len(contexts) != 1 || contexts[0].Name != "fake" || !contexts[0].Current {
+		t.Fatalf("list contexts: got %+v, want the fake context only", contexts)
+	}
+	if contexts[0].ClusterUID != "6f1d2a4e-0c1b-4d1e-9a51-3c7e0e1f0002" {
+		t.Errorf("list contexts: clusterUID = %q, want the kube-system namespace UID", contexts[0].ClusterUID)
+	}
+
+	var resources restkuberesources.ResourceList
+	if status := doRequest(t, http.MethodGet, api+"fake/resources/", "", &resources); status != http.StatusOK {
+		t.Fatalf("list resources: status = %d", status)
+	}
+	found := map[string]bool{}
+	for _, list := range resources.Resources {
+		for _, res := range list.APIResources {
+			found[list.GroupVersion+"/"+res.Name] = true
+		}
+	}
+	for _, want := range []string{"apps/v1/deployments", "apps/v1/replicasets", "v1/pods", "v1/services", "v1/configmaps", "v1/namespaces"} {
+		if !found[want] {
+			t.Errorf("list resources: %s is missing", want)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantNames  []string
+	}{
+		{"list namespaced", "fake/resources/core/v1/namespaces/default/pods/", http.StatusOK, []string{"web-5d8f7c9b6-x2v7q"}},
+		{"list all namespaces", "fake/resources/apps/v1/deployments/", http.StatusOK, []string{"web"}},
+		{"list cluster-scoped", "fake/resources/core/v1/namespaces/", http.StatusOK, []string{"default", "kube-system"}},
+		{"list empty namespace", "fake/resources/core/v1/namespaces/kube-system/pods/", http.StatusOK, nil},
+		{"unknown context", "prod/resources/core/v1/namespaces/default/pods/", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []unstructured.Unstructured
+			status := doRequest(t, http.MethodGet, api+tt.path, "", &objs)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+
+			var names []string
+			for _, obj := range objs {
+				names = append(names, obj.GetName())
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+
+	var deploy struct {
+		Spec struct {
+			Replicas int `json:"replicas"`
+		} `json:"spec"`
+	}
+	if status := doRequest(t, http.MethodGet, api+"fake/resources/apps/v1/namespaces/default/deployments/web/", "", &deploy); status != http.StatusOK {
+		t.Fatalf("get deployment: status = %d", status)
+	}
+	if deploy.Spec.Replicas != 1 {
+		t.Errorf("get deployment: spec.replicas = %d, want 1", deploy.Spec.Replicas)
+	}
+
+	if status := doRequest(t, http.MethodGet, api+"fake/resources/apps/v1/namespaces/default/deployments/nope/", "", nil); status != http.StatusNotFound {
+		t.Errorf("get missing deployment: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+// The related objects are served from the shared informer caches,
+// which must follow the changes.
+func TestRelations(t *testing.T) {
+	server, _ := newTestServer(t)
+	api := server.URL + "/api/kube/v1/contexts/fake/"
+
+	related := func() map[string]bool {
+		t.Helper()
+
+		var graph struct {
+			Nodes []struct {
+				Kind string `json:"kind"`
+				Name string `json:"name"`
+			} `json:"nodes"`
+		}
+		if status := doRequest(t, http.MethodGet, api+"relations/apps/v1/namespaces/default/deployments/web/", "", &graph); status != http.StatusOK {
+			t.Fatalf("relations: status = %d", status)
+		}
+
+		nodes := map[string]bool{}
+		for _, node := range graph.Nodes {
+			nodes[node.Kind+"/"+node.Name] = true
+		}
+		return nodes
+	}
+
+	const pod = "Pod/web-5d8f7c9b6-x2v7q"
+
+	nodes := related()
+	for _, want := range []string{"Deployment/web", "ReplicaSet/web-5d8f7c9b6", pod} {
+		if !nodes[want] {
+			t.Errorf("related: %s is missing (got %v)", want, nodes)
+		}
+	}
+
+	if status := doRequest(t, http.MethodDelete, api+"resources/core/v1/namespaces/default/pods/web-5d8f7c9b6-x2v7q/", "", nil); status != http.StatusNoContent {
+		t.Fatalf("delete pod: status = %d", status)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for related()[pod] {
+		if time.Now().After(deadline) {
+			t.Fatalf("related: %s is still there after the deletion", pod)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Changes made via the REST API must show up in the watch stream
+// (and in the audit log).
+func TestWatchRPC(t *testing.T) {
+	server, auditLog := newTestServer(t)
+	api := server.URL + "/api/kube/v1/contexts/fake/resources/core/v1/namespaces/default/configmaps/"
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/stream/v1/", nil)
+	if err != nil {
+		t.Fatalf("couldn't connect to the stream: %v", err)
+	}
+	defer conn.Close()
+
+	call := `{"type": "call", "id": "w1", "method": "kubeObjects.watch", "params": {
+		"context": "fake", "group": "core", "version": "v1", "resource": "configmaps", "namespace": "default"
+	}}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(call)); err != nil {
+		t.Fatalf("couldn't send the watch call: %v", err)
+	}
+
+	obj := expectEvent(t, conn, "w1", "added")
+	if obj.GetName() != "web-config" {
+		t.Fatalf("added: got %s, want web-config", obj.GetName())
+	}
+
+	if err := unstructured.SetNestedField(obj.Object, "debug", "data", "LOG_LEVEL"); err != nil {
+		t.Fatal(err)
+	}
+	body, err := obj.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := doRequest(t, http.MethodPut, api+"web-config/", string(body), nil); status != http.StatusOK {
+		t.Fatalf("update: status = %d", status)
+	}
+
+	obj = expectEvent(t, conn, "w1", "updated")
+	if level, _, _ := unstructured.NestedString(obj.Object, "data", "LOG_LEVEL"); level != "debug" {
+		t.Errorf("updated: data.LOG_LEVEL = %q, want debug", level)
+	}
+
+	if status := doRequest(t, http.MethodDelete, api+"web-config/", "", nil); status != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", status)
+	}
+	expectEvent(t, conn, "w1", "deleted")
+
+	if status := doRequest(t, http.MethodGet, api+"web-config/", "", nil); status != http.StatusNotFound {
+		t.Errorf("get deleted: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	entries := auditLog.Find(audit.Query{Context: "fake"})
+	if len(entries) != 2 || entries[0].Verb != "delete" || entries[1].Verb != "update" {
+		t.Fatalf("audit log: got %+v, want delete and update entries", entries)
+	}
+	if entries[1].Outcome != audit.OutcomeSucceeded || len(entries[1].Changes) == 0 {
+		t.Errorf("audit log: update entry %+v has no changes", entries[1])
+	}
+}
+
+func expectEvent(t *testing.T, conn *websocket.Conn, id string, event string) *unstructured.Unstructured {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s event: %v", event, err)
+		}
+
+		var reply struct {
+			ID     string `json:"id"`
+			Error  string `json:"error"`
+			Result struct {
+				Event string `json:"event"`
+				JSON  string `json:"json"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(data, &reply); err != nil {
+			t.Fatalf("malformed stream message %s: %v", data, err)
+		}
+		if reply.ID != id {
+			continue
+		}
+		if reply.Error != "" {
+			t.Fatalf("waiting for %s event: call failed: %s", event, reply.Error)
+		}
+		if reply.Result.Event != event {
+			continue // E.g., a resync.
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON([]byte(reply.Result.JSON)); err != nil {
+			t.Fatalf("%s event: malformed object: %v", event, err)
+		}
+		return obj
+	}
+}
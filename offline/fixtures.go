@@ -0,0 +1,114 @@
+package offline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// LoadFixtures reads the objects from all YAML (and JSON) files in the
+// directory and its subdirectories. Files may contain several documents
+// and lists (kind: List) - i.e., anything 'kubectl get -o yaml' prints.
+//
+// Namespaced objects must have metadata.namespace set - otherwise,
+// there is no way to tell them from cluster-scoped ones.
+func LoadFixtures(dir string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fileObjs, err := decodeFixtures(f)
+		if err != nil {
+			return fmt.Errorf("couldn't load fixtures from %s: %w", path, err)
+		}
+		objs = append(objs, fileObjs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objs, nil
+}
+
+func decodeFixtures(r io.Reader) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	add := func(obj *unstructured.Unstructured) error {
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			return errors.New("object must have apiVersion, kind, and metadata.name")
+		}
+		objs = append(objs, obj)
+		return nil
+	}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var doc json.RawMessage
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, err
+		}
+		if len(doc) == 0 || string(doc) == "null" {
+			continue
+		}
+
+		// Unlike a plain json.Unmarshal, keeps integers int64 (as the API does).
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(doc); err != nil {
+			return nil, err
+		}
+		if obj.IsList() {
+			err := obj.EachListItem(func(item runtime.Object) error {
+				return add(item.(*unstructured.Unstructured))
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := add(obj); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ClusterUIDOf returns the UID of the kube-system namespace (what the pool
+// uses as the cluster UID for real clusters) if it's among the objects.
+func ClusterUIDOf(objs []*unstructured.Unstructured) string {
+	for _, obj := range objs {
+		if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Namespace" && obj.GetName() == "kube-system" {
+			return string(obj.GetUID())
+		}
+	}
+	return ""
+}
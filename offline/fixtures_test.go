@@ -0,0 +1,102 @@
+package offline
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeFixtures(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name: "multiple documents",
+			input: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: one
+---
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: two
+`,
+			wantNames: []string{"one", "two"},
+		},
+		{
+			name: "list",
+			input: `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: web
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: web-0
+`,
+			wantNames: []string{"web", "web-0"},
+		},
+		{
+			name:      "JSON",
+			input:     `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "default"}}`,
+			wantNames: []string{"default"},
+		},
+		{
+			name:    "no name",
+			input:   "apiVersion: v1\nkind: ConfigMap\n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			input:   "apiVersion: [v1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := decodeFixtures(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeFixtures() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var names []string
+			for _, obj := range objs {
+				names = append(names, obj.GetName())
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+// The fake clients (and the handlers) expect integers the way the API
+// server returns them.
+func TestDecodeFixturesKeepsIntegers(t *testing.T) {
+	objs, err := decodeFixtures(strings.NewReader(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+`))
+	if err != nil {
+		t.Fatalf("decodeFixtures() failed: %v", err)
+	}
+
+	replicas, ok := objs[0].Object["spec"].(map[string]interface{})["replicas"].(int64)
+	if !ok || replicas != 3 {
+		t.Errorf("spec.replicas = %#v, want int64(3)", objs[0].Object["spec"].(map[string]interface{})["replicas"])
+	}
+}
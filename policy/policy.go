@@ -0,0 +1,169 @@
+// Package policy decides which actions (create, update, delete, etc.)
+// kexp lets its users perform - on top of what the kubeconfig's RBAC
+// permissions allow. Reads (get, list, watch) are always allowed.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbPatch  = "patch"
+	VerbDelete = "delete"
+	VerbExec   = "exec"
+
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+
+	wildcard = "*"
+)
+
+var actionVerbs = []string{VerbCreate, VerbUpdate, VerbPatch, VerbDelete, VerbExec}
+
+// Rule matches actions by verb, context, resource, and namespace.
+// An empty list (or "*") matches anything. Example:
+//
+//	rules:
+//	  - effect: deny
+//	    verbs: [delete]
+//	    namespaces: [kube-system]
+//	  - effect: deny
+//	    verbs: [update, delete]
+//	    resources: [secrets, deployments.apps]
+//	    contexts: [prod]
+//
+// Resources are in the resource.group notation (the group is omitted
+// for the core API group). A resource without a group matches it in
+// any group. Cluster-scoped objects have no namespace, so they match
+// only the rules without namespaces - except for the Namespace objects
+// that match the rules with their own names (deleting a namespace
+// deletes everything in it).
+type Rule struct {
+	Effect     string   `json:"effect"`
+	Verbs      []string `json:"verbs,omitempty"`
+	Contexts   []string `json:"contexts,omitempty"`
+	Resources  []string `json:"resources,omitempty"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// Action is what a request is about to do.
+type Action struct {
+	Verb      string
+	Context   string
+	Group     string
+	Resource  string
+	Namespace string
+	Name      string
+}
+
+// Policy evaluates the rules in order - the first matching rule wins.
+// Actions matching no rules are allowed.
+type Policy struct {
+	// Denies all actions regardless of the rules.
+	ReadOnly bool `json:"readOnly"`
+
+	Rules []Rule `json:"rules"`
+}
+
+// Load reads the rules from a YAML (or JSON) file.
+func Load(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read policy file: %w", err)
+	}
+	defer f.Close()
+
+	var policy Policy
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&policy); err != nil {
+		return nil, fmt.Errorf("couldn't decode policy file %s: %w", path, err)
+	}
+	if policy.Rules == nil {
+		policy.Rules = []Rule{}
+	}
+	for i, rule := range policy.Rules {
+		if err := validate(rule); err != nil {
+			return nil, fmt.Errorf("policy rule #%d: %w", i+1, err)
+		}
+	}
+
+	return &policy, nil
+}
+
+func validate(rule Rule) error {
+	if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+		return fmt.Errorf("effect must be %q or %q", EffectAllow, EffectDeny)
+	}
+
+	for _, verb := range rule.Verbs {
+		if verb != wildcard && !slices.Contains(actionVerbs, verb) {
+			return fmt.Errorf(
+				"unknown verb %q - expected one of %s (reads are always allowed)",
+				verb, strings.Join(actionVerbs, ", "),
+			)
+		}
+	}
+	return nil
+}
+
+// Allows tells if the action is permitted. The nil policy allows everything.
+func (p *Policy) Allows(action Action) bool {
+	if p == nil || !slices.Contains(actionVerbs, action.Verb) {
+		return true
+	}
+
+	if p.ReadOnly {
+		return false
+	}
+
+	for _, rule := range p.Rules {
+		if rule.matches(action) {
+			return rule.Effect == EffectAllow
+		}
+	}
+	return true
+}
+
+func (r Rule) matches(action Action) bool {
+	if !matchesAny(r.Verbs, action.Verb) || !matchesAny(r.Contexts, action.Context) {
+		return false
+	}
+
+	if ns := action.affectedNamespace(); len(r.Namespaces) > 0 && (ns == "" || !matchesAny(r.Namespaces, ns)) {
+		return false
+	}
+
+	if len(r.Resources) == 0 {
+		return true
+	}
+	for _, res := range r.Resources {
+		if res == wildcard {
+			return true
+		}
+
+		name, group, hasGroup := strings.Cut(res, ".")
+		if name == action.Resource && (!hasGroup || group == action.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+// affectedNamespace is the object's namespace or, for
+// the Namespace objects, the namespace itself.
+func (a Action) affectedNamespace() string {
+	if a.Group == "" && a.Resource == "namespaces" && a.Namespace == "" {
+		return a.Name
+	}
+	return a.Namespace
+}
+
+func matchesAny(patterns []string, value string) bool {
+	return len(patterns) == 0 || slices.Contains(patterns, wildcard) || slices.Contains(patterns, value)
+}
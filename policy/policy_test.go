@@ -0,0 +1,166 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAllows(t *testing.T) {
+	rules := &Policy{Rules: []Rule{
+		{Effect: EffectDeny, Verbs: []string{VerbDelete}, Namespaces: []string{"kube-system"}},
+		{Effect: EffectAllow, Verbs: []string{VerbUpdate}, Resources: []string{"configmaps"}, Contexts: []string{"prod"}},
+		{Effect: EffectDeny, Verbs: []string{VerbUpdate, VerbDelete}, Resources: []string{"secrets", "deployments.apps"}, Contexts: []string{"prod"}},
+		{Effect: EffectDeny, Verbs: []string{wildcard}, Contexts: []string{"prod"}, Namespaces: []string{wildcard}},
+	}}
+
+	tests := []struct {
+		name   string
+		policy *Policy
+		action Action
+		want   bool
+	}{
+		{
+			name:   "nil policy",
+			policy: nil,
+			action: Action{Verb: VerbDelete, Context: "prod", Resource: "pods", Namespace: "default"},
+			want:   true,
+		},
+		{
+			name:   "read-only",
+			policy: &Policy{ReadOnly: true},
+			action: Action{Verb: VerbUpdate, Context: "dev", Resource: "pods", Namespace: "default"},
+			want:   false,
+		},
+		{
+			name:   "read-only allows reads",
+			policy: &Policy{ReadOnly: true},
+			action: Action{Verb: "get", Context: "dev", Resource: "pods", Namespace: "default"},
+			want:   true,
+		},
+		{
+			name:   "no matching rules",
+			policy: rules,
+			action: Action{Verb: VerbDelete, Context: "dev", Resource: "pods", Namespace: "default"},
+			want:   true,
+		},
+		{
+			name:   "denied namespace",
+			policy: rules,
+			action: Action{Verb: VerbDelete, Context: "dev", Resource: "pods", Namespace: "kube-system", Name: "coredns-1"},
+			want:   false,
+		},
+		{
+			name:   "denied namespace - other verb",
+			policy: rules,
+			action: Action{Verb: VerbUpdate, Context: "dev", Resource: "pods", Namespace: "kube-system"},
+			want:   true,
+		},
+		{
+			name:   "the denied namespace itself",
+			policy: rules,
+			action: Action{Verb: VerbDelete, Context: "dev", Group: "", Resource: "namespaces", Name: "kube-system"},
+			want:   false,
+		},
+		{
+			name:   "another namespace object",
+			policy: rules,
+			action: Action{Verb: VerbDelete, Context: "dev", Group: "", Resource: "namespaces", Name: "default"},
+			want:   true,
+		},
+		{
+			name:   "other cluster-scoped object named as the namespace",
+			policy: rules,
+			action: Action{Verb: VerbDelete, Context: "dev", Group: "rbac.authorization.k8s.io", Resource: "clusterroles", Name: "kube-system"},
+			want:   true,
+		},
+		{
+			name:   "first matching rule wins",
+			policy: rules,
+			action: Action{Verb: VerbUpdate, Context: "prod", Resource: "configmaps", Namespace: "default"},
+			want:   true,
+		},
+		{
+			name:   "resource without group matches any group",
+			policy: rules,
+			action: Action{Verb: VerbUpdate, Context: "prod", Resource: "secrets", Namespace: "default"},
+			want:   false,
+		},
+		{
+			name:   "resource with group",
+			policy: rules,
+			action: Action{Verb: VerbDelete, Context: "prod", Group: "apps", Resource: "deployments", Name: "web", Namespace: "default"},
+			want:   false,
+		},
+		{
+			name:   "resource with another group",
+			policy: &Policy{Rules: rules.Rules[2:3]},
+			action: Action{Verb: VerbDelete, Context: "prod", Group: "extensions", Resource: "deployments", Namespace: "default"},
+			want:   true,
+		},
+		{
+			name:   "wildcard namespaces don't match cluster-scoped objects",
+			policy: &Policy{Rules: rules.Rules[3:]},
+			action: Action{Verb: VerbDelete, Context: "prod", Group: "rbac.authorization.k8s.io", Resource: "clusterroles", Name: "admin"},
+			want:   true,
+		},
+		{
+			name:   "wildcard namespaces match namespace objects",
+			policy: &Policy{Rules: rules.Rules[3:]},
+			action: Action{Verb: VerbDelete, Context: "prod", Resource: "namespaces", Name: "default"},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Allows(tt.action); got != tt.want {
+				t.Errorf("Allows(%+v) = %v, want %v", tt.action, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: "rules:\n  - effect: deny\n    verbs: [delete]\n    namespaces: [kube-system]\n",
+		},
+		{
+			name:    "no rules",
+			content: "readOnly: true\n",
+		},
+		{
+			name:    "unknown effect",
+			content: "rules:\n  - effect: maybe\n",
+			wantErr: true,
+		},
+		{
+			name:    "read verb",
+			content: "rules:\n  - effect: deny\n    verbs: [get]\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			pol, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && pol.Rules == nil {
+				t.Error("Load() returned nil rules")
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package presets
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/iximiuz/kexp/relations"
+)
+
+// Definition is a user-defined graph preset. Example:
+//
+//	name: my-app
+//	description: My app and its database
+//	extends: application
+//	kinds:
+//	  - Database.example.com
+//	ownership: true
+//	selectors:
+//	  - from: Service
+//	    to: Pod
+//	    path: "{.spec.selector}"
+//	references:
+//	  - from: Database.example.com
+//	    to: Secret
+//	    path: "{.spec.credentials.secretName}"
+//
+// Kinds are in the Kind.group notation (the group is omitted for
+// the core API group).
+type Definition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// A built-in preset to start from.
+	Extends string `json:"extends,omitempty"`
+
+	// Kinds of objects to include. Extended presets' kinds
+	// are included implicitly.
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Kinds that are not traversed through (see relations.HubKinds).
+	Hubs []string `json:"hubs,omitempty"`
+
+	// Relate objects of the included kinds by owner references.
+	Ownership bool `json:"ownership,omitempty"`
+
+	// The path points to a label selector (either a plain map
+	// of labels or a metav1.LabelSelector) in the from object.
+	Selectors []FieldRelation `json:"selectors,omitempty"`
+
+	// The path points to name(s) of the to objects in the from object.
+	References []FieldRelation `json:"references,omitempty"`
+
+	// Where the definition comes from (set by the registry).
+	Source string `json:"source,omitempty"`
+}
+
+type FieldRelation struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Path string `json:"path"`
+}
+
+// Compile turns the definition into a relations.Preset.
+func Compile(def Definition) (relations.Preset, error) {
+	if def.Name == "" {
+		return relations.Preset{}, fmt.Errorf("preset name is required")
+	}
+
+	preset := relations.Preset{Name: def.Name}
+	kinds := []schema.GroupKind{}
+
+	if def.Extends != "" {
+		base, found := relations.LookupPreset(def.Extends)
+		if !found {
+			return relations.Preset{}, fmt.Errorf("preset %q extends unknown preset %q", def.Name, def.Extends)
+		}
+
+		preset.Rules = append(preset.Rules, base.Rules...)
+		preset.Hubs = append(preset.Hubs, base.Hubs...)
+		kinds = append(kinds, base.Kinds()...)
+	}
+
+	for _, k := range def.Kinds {
+		kinds = append(kinds, schema.ParseGroupKind(k))
+	}
+
+	for _, k := range def.Hubs {
+		preset.Hubs = append(preset.Hubs, schema.ParseGroupKind(k))
+	}
+	if len(preset.Hubs) == 0 {
+		preset.Hubs = relations.HubKinds
+	}
+
+	if def.Ownership {
+		// The extended preset's ownership rules are kept as is.
+		owns := map[[2]schema.GroupKind]bool{}
+		for _, rule := range preset.Rules {
+			if rule.Type == relations.RelationOwnership {
+				owns[[2]schema.GroupKind{rule.From, rule.To}] = true
+			}
+		}
+
+		for _, owner := range kinds {
+			for _, dependent := range kinds {
+				if owns[[2]schema.GroupKind{owner, dependent}] {
+					continue
+				}
+				owns[[2]schema.GroupKind{owner, dependent}] = true
+
+				preset.Rules = append(preset.Rules, relations.OwnershipRule(owner, dependent))
+			}
+		}
+	}
+
+	for _, rel := range def.Selectors {
+		path, err := parsePath(rel)
+		if err != nil {
+			return relations.Preset{}, fmt.Errorf("preset %q: %w", def.Name, err)
+		}
+
+		preset.Rules = append(preset.Rules, relations.Rule{
+			Type:          relations.RelationSelection,
+			From:          schema.ParseGroupKind(rel.From),
+			To:            schema.ParseGroupKind(rel.To),
+			SameNamespace: true,
+			Match: func(from, to *unstructured.Unstructured) bool {
+				for _, v := range findValues(path, from) {
+					if selectorMatches(v, to) {
+						return true
+					}
+				}
+				return false
+			},
+		})
+	}
+
+	for _, rel := range def.References {
+		path, err := parsePath(rel)
+		if err != nil {
+			return relations.Preset{}, fmt.Errorf("preset %q: %w", def.Name, err)
+		}
+
+		preset.Rules = append(preset.Rules, relations.Rule{
+			Type:          relations.RelationReference,
+			From:          schema.ParseGroupKind(rel.From),
+			To:            schema.ParseGroupKind(rel.To),
+			SameNamespace: true,
+			Match: func(from, to *unstructured.Unstructured) bool {
+				for _, v := range findValues(path, from) {
+					if name, ok := v.(string); ok && name == to.GetName() {
+						return true
+					}
+				}
+				return false
+			},
+		})
+	}
+
+	return preset, nil
+}
+
+func parsePath(rel FieldRelation) (string, error) {
+	if rel.From == "" || rel.To == "" {
+		return "", fmt.Errorf("relation %q: both from and to kinds are required", rel.Path)
+	}
+
+	if _, err := newJSONPath(rel.Path); err != nil {
+		return "", fmt.Errorf("invalid JSONPath %q: %w", rel.Path, err)
+	}
+	return rel.Path, nil
+}
+
+func newJSONPath(path string) (*jsonpath.JSONPath, error) {
+	jp := jsonpath.New("preset").AllowMissingKeys(true)
+	if err := jp.Parse(path); err != nil {
+		return nil, err
+	}
+	return jp, nil
+}
+
+// JSONPath instances keep the execution state, so they can't be
+// shared between (concurrently running) rule evaluations.
+func findValues(path string, obj *unstructured.Unstructured) []interface{} {
+	jp, err := newJSONPath(path)
+	if err != nil {
+		return nil
+	}
+
+	results, err := jp.FindResults(obj.Object)
+	if err != nil {
+		return nil
+	}
+
+	var values []interface{}
+	for _, rs := range results {
+		for _, r := range rs {
+			if !r.IsValid() || !r.CanInterface() {
+				continue
+			}
+
+			if list, ok := r.Interface().([]interface{}); ok {
+				values = append(values, list...)
+			} else {
+				values = append(values, r.Interface())
+			}
+		}
+	}
+	return values
+}
+
+func selectorMatches(v interface{}, obj *unstructured.Unstructured) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return false
+	}
+
+	_, hasMatchLabels := m["matchLabels"]
+	_, hasMatchExpressions := m["matchExpressions"]
+	if hasMatchLabels || hasMatchExpressions {
+		ls := metav1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &ls); err != nil {
+			return false
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(&ls)
+		if err != nil || selector.Empty() {
+			return false
+		}
+		return selector.Matches(labels.Set(obj.GetLabels()))
+	}
+
+	set := map[string]string{}
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			set[k] = s
+		}
+	}
+	return relations.SelectorMatches(set, obj)
+}
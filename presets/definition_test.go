@@ -0,0 +1,249 @@
+package presets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/iximiuz/kexp/relations"
+)
+
+func TestCompile(t *testing.T) {
+	application, _ := relations.LookupPreset(relations.PresetApplication)
+
+	tests := []struct {
+		name    string
+		def     Definition
+		wantErr string
+
+		wantRules int
+		wantHubs  []schema.GroupKind
+	}{
+		{
+			name:    "no name",
+			def:     Definition{Kinds: []string{"Pod"}},
+			wantErr: "name is required",
+		},
+		{
+			name:    "unknown base",
+			def:     Definition{Name: "my-app", Extends: "nope"},
+			wantErr: `extends unknown preset "nope"`,
+		},
+		{
+			name: "relation without kinds",
+			def: Definition{Name: "my-app", Selectors: []FieldRelation{
+				{From: "Service", Path: "{.spec.selector}"},
+			}},
+			wantErr: "both from and to kinds are required",
+		},
+		{
+			name: "invalid JSONPath",
+			def: Definition{Name: "my-app", References: []FieldRelation{
+				{From: "Database.example.com", To: "Secret", Path: "{.spec.credentials"},
+			}},
+			wantErr: "invalid JSONPath",
+		},
+		{
+			name:      "ownership between own kinds",
+			def:       Definition{Name: "my-app", Kinds: []string{"Database.example.com", "Secret"}, Ownership: true},
+			wantRules: 4,
+			wantHubs:  relations.HubKinds,
+		},
+		{
+			// Only the pairs the base preset doesn't relate yet.
+			name:      "ownership on top of a base",
+			def:       Definition{Name: "my-app", Extends: relations.PresetApplication, Ownership: true},
+			wantRules: len(application.Rules) + len(application.Kinds())*len(application.Kinds()) - ownershipRules(application),
+			wantHubs:  application.Hubs,
+		},
+		{
+			name: "relations and hubs",
+			def: Definition{
+				Name:       "my-app",
+				Hubs:       []string{"Secret"},
+				Selectors:  []FieldRelation{{From: "Service", To: "Pod", Path: "{.spec.selector}"}},
+				References: []FieldRelation{{From: "Database.example.com", To: "Secret", Path: "{.spec.credentials.secretName}"}},
+			},
+			wantRules: 2,
+			wantHubs:  []schema.GroupKind{{Kind: "Secret"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preset, err := Compile(tt.def)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Compile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile() failed: %v", err)
+			}
+
+			if len(preset.Rules) != tt.wantRules {
+				t.Errorf("rules = %d, want %d", len(preset.Rules), tt.wantRules)
+			}
+			if !reflect.DeepEqual(preset.Hubs, tt.wantHubs) {
+				t.Errorf("hubs = %v, want %v", preset.Hubs, tt.wantHubs)
+			}
+
+			seen := map[string]bool{}
+			for _, rule := range preset.Rules {
+				key := string(rule.Type) + " " + rule.From.String() + " -> " + rule.To.String()
+				if rule.Type == relations.RelationOwnership && seen[key] {
+					t.Errorf("duplicate rule %s", key)
+				}
+				seen[key] = true
+			}
+		})
+	}
+}
+
+func ownershipRules(preset relations.Preset) int {
+	n := 0
+	for _, rule := range preset.Rules {
+		if rule.Type == relations.RelationOwnership {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCompiledRules(t *testing.T) {
+	preset, err := Compile(Definition{
+		Name:       "my-app",
+		Selectors:  []FieldRelation{{From: "Database.example.com", To: "Pod", Path: "{.spec.selector}"}},
+		References: []FieldRelation{{From: "Database.example.com", To: "Secret", Path: "{.spec.credentials[*].secretName}"}},
+	})
+	if err != nil {
+		t.Fatalf("Compile() failed: %v", err)
+	}
+	selects, references := preset.Rules[0], preset.Rules[1]
+
+	db := newObject("Database", "db", map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "db"}},
+		"credentials": []interface{}{
+			map[string]interface{}{"secretName": "db-admin"},
+			map[string]interface{}{"secretName": "db-reader"},
+		},
+	})
+
+	if !selects.Match(db, newObject("Pod", "db-0", nil, "app", "db")) {
+		t.Error("selector rule didn't match the selected pod")
+	}
+	if selects.Match(db, newObject("Pod", "web-0", nil, "app", "web")) {
+		t.Error("selector rule matched another pod")
+	}
+	if !references.Match(db, newObject("Secret", "db-reader", nil)) {
+		t.Error("reference rule didn't match the referenced secret")
+	}
+	if references.Match(db, newObject("Secret", "web", nil)) {
+		t.Error("reference rule matched another secret")
+	}
+}
+
+func TestFindValues(t *testing.T) {
+	obj := newObject("Database", "db", map[string]interface{}{
+		"secretName": "db-admin",
+		"replicas":   int64(3),
+		"names":      []interface{}{"a", "b"},
+		"credentials": []interface{}{
+			map[string]interface{}{"secretName": "db-admin"},
+			map[string]interface{}{"secretName": "db-reader"},
+		},
+	})
+
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{"{.spec.secretName}", []interface{}{"db-admin"}},
+		{"{.spec.replicas}", []interface{}{int64(3)}},
+		{"{.spec.names}", []interface{}{"a", "b"}},
+		{"{.spec.credentials[*].secretName}", []interface{}{"db-admin", "db-reader"}},
+		{"{.spec.nope}", nil},
+		{"{.spec.credentials", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := findValues(tt.path, obj); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findValues() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	pod := newObject("Pod", "web-0", nil, "app", "web", "tier", "frontend")
+
+	tests := []struct {
+		name     string
+		selector interface{}
+		want     bool
+	}{
+		{"plain map", map[string]interface{}{"app": "web"}, true},
+		{"plain map mismatch", map[string]interface{}{"app": "db"}, false},
+		{"matchLabels", map[string]interface{}{"matchLabels": map[string]interface{}{"tier": "frontend"}}, true},
+		{
+			name: "matchExpressions",
+			selector: map[string]interface{}{"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "app", "operator": "In", "values": []interface{}{"web", "api"}},
+			}},
+			want: true,
+		},
+		{
+			name: "matchExpressions mismatch",
+			selector: map[string]interface{}{"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "tier", "operator": "NotIn", "values": []interface{}{"frontend"}},
+			}},
+			want: false,
+		},
+		{
+			name: "invalid operator",
+			selector: map[string]interface{}{"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "app", "operator": "Like", "values": []interface{}{"web"}},
+			}},
+			want: false,
+		},
+		// Empty selectors match nothing (unlike in label selector semantics).
+		{"empty map", map[string]interface{}{}, false},
+		{"empty matchLabels", map[string]interface{}{"matchLabels": map[string]interface{}{}}, false},
+		{"not a map", "app=web", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectorMatches(tt.selector, pod); got != tt.want {
+				t.Errorf("selectorMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newObject builds a namespaced object with the spec and the labels
+// (given as key-value pairs).
+func newObject(kind, name string, spec map[string]interface{}, labels ...string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if spec != nil {
+		obj.Object["spec"] = spec
+	}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetNamespace("default")
+	obj.SetName(name)
+
+	if len(labels) > 0 {
+		set := map[string]string{}
+		for i := 0; i+1 < len(labels); i += 2 {
+			set[labels[i]] = labels[i+1]
+		}
+		obj.SetLabels(set)
+	}
+	return obj
+}
@@ -0,0 +1,279 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/iximiuz/kexp/kubeclient"
+	"github.com/iximiuz/kexp/relations"
+)
+
+// ConfigMaps with this label set to "true" hold preset definitions
+// (every data key is a YAML file with one or more definitions).
+const ConfigMapLabel = "kexp.iximiuz.com/preset"
+
+// The presets from the context's ConfigMaps are re-read at most this often.
+const configMapPresetsTTL = 30 * time.Second
+
+const (
+	SourceBuiltin   = "builtin"
+	sourceFile      = "file"
+	sourceConfigMap = "configmap"
+)
+
+var ErrNotFound = errors.New("preset not found")
+
+type Entry struct {
+	Definition Definition
+	Preset     relations.Preset
+}
+
+// Registry resolves presets by name. Built-in presets come first,
+// then the presets from the presets dir, and then the presets from
+// the context's ConfigMaps. Presets can't shadow the ones that come
+// before them.
+type Registry struct {
+	entries []Entry
+	logger  *logrus.Entry
+
+	mux sync.Mutex
+	// All presets of the (recently used) contexts.
+	cached map[*kubeclient.Context]cachedEntries
+}
+
+type cachedEntries struct {
+	entries []Entry
+	expires time.Time
+}
+
+// NewRegistry loads *.yaml, *.yml, and *.json files from the dir
+// (if not empty). Invalid definitions are skipped with a warning.
+func NewRegistry(dir string, logger *logrus.Entry) (*Registry, error) {
+	r := &Registry{
+		logger: logger.WithField("component", "presets"),
+		cached: map[*kubeclient.Context]cachedEntries{},
+	}
+
+	for _, preset := range relations.BuiltinPresets() {
+		kinds := []string{}
+		for _, gk := range preset.Kinds() {
+			kinds = append(kinds, gk.String())
+		}
+		sort.Strings(kinds)
+
+		r.entries = append(r.entries, Entry{
+			Definition: Definition{
+				Name:   preset.Name,
+				Kinds:  kinds,
+				Source: SourceBuiltin,
+			},
+			Preset: preset,
+		})
+	}
+
+	if dir == "" {
+		return r, nil
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read presets dir: %w", err)
+	}
+
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		if file.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		logger := r.logger.WithField("file", path)
+
+		f, err := os.Open(path)
+		if err != nil {
+			logger.WithError(err).Warn("Couldn't open presets file")
+			continue
+		}
+
+		defs, err := Parse(f, sourceFile+":"+path)
+		f.Close()
+		if err != nil {
+			logger.WithError(err).Warn("Couldn't parse presets file")
+			continue
+		}
+
+		r.entries = r.add(r.entries, defs)
+	}
+
+	return r, nil
+}
+
+// Parse reads a (multi-document) YAML or JSON stream of definitions.
+func Parse(reader io.Reader, source string) ([]Definition, error) {
+	var defs []Definition
+
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+	for {
+		def := Definition{}
+		if err := decoder.Decode(&def); err != nil {
+			if errors.Is(err, io.EOF) {
+				return defs, nil
+			}
+			return nil, err
+		}
+
+		if reflect.DeepEqual(def, Definition{}) {
+			continue // An empty document.
+		}
+
+		def.Source = source
+		defs = append(defs, def)
+	}
+}
+
+// List returns all presets available in the context. The result
+// is shared between the callers and must not be modified.
+func (r *Registry) List(ctx context.Context, kctx *kubeclient.Context) ([]Entry, error) {
+	r.mux.Lock()
+	cached, found := r.cached[kctx]
+	r.mux.Unlock()
+
+	if found && time.Now().Before(cached.expires) {
+		return cached.entries, nil
+	}
+
+	defs, err := r.configMapDefinitions(ctx, kctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := r.add(append([]Entry{}, r.entries...), defs)
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := time.Now()
+	for k, cached := range r.cached {
+		if now.After(cached.expires) {
+			delete(r.cached, k)
+		}
+	}
+	r.cached[kctx] = cachedEntries{entries: entries, expires: now.Add(configMapPresetsTTL)}
+
+	return entries, nil
+}
+
+// Lookup finds a preset by name. The empty name stands for the default preset.
+// The context's ConfigMaps are consulted only if there is no such built-in
+// or file preset.
+func (r *Registry) Lookup(ctx context.Context, kctx *kubeclient.Context, name string) (relations.Preset, error) {
+	if name == "" {
+		name = relations.PresetRelated
+	}
+
+	if i := indexOf(r.entries, name); i >= 0 {
+		return r.entries[i].Preset, nil
+	}
+
+	entries, err := r.List(ctx, kctx)
+	if err != nil {
+		return relations.Preset{}, err
+	}
+
+	if i := indexOf(entries, name); i >= 0 {
+		return entries[i].Preset, nil
+	}
+	return relations.Preset{}, fmt.Errorf("%w: %q", ErrNotFound, name)
+}
+
+func (r *Registry) add(entries []Entry, defs []Definition) []Entry {
+	for _, def := range defs {
+		logger := r.logger.
+			WithField("preset", def.Name).
+			WithField("source", def.Source)
+
+		if i := indexOf(entries, def.Name); i >= 0 {
+			logger.
+				WithField("existingSource", entries[i].Definition.Source).
+				Warn("Preset is already defined - skipping")
+			continue
+		}
+
+		preset, err := Compile(def)
+		if err != nil {
+			logger.WithError(err).Warn("Invalid preset definition - skipping")
+			continue
+		}
+
+		entries = append(entries, Entry{Definition: def, Preset: preset})
+	}
+	return entries
+}
+
+func (r *Registry) configMapDefinitions(ctx context.Context, kctx *kubeclient.Context) ([]Definition, error) {
+	clientset, err := kctx.Clientset()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := clientset.CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: ConfigMapLabel + "=true",
+	})
+	if err != nil {
+		// Not every user can list ConfigMaps cluster-wide.
+		if apierrors.IsForbidden(err) {
+			r.logger.
+				WithField("context", kctx.Name()).
+				Debug("Not allowed to list preset ConfigMaps")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var defs []Definition
+	for _, cm := range list.Items {
+		keys := make([]string, 0, len(cm.Data))
+		for key := range cm.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			source := fmt.Sprintf("%s:%s/%s/%s", sourceConfigMap, cm.Namespace, cm.Name, key)
+
+			parsed, err := Parse(strings.NewReader(cm.Data[key]), source)
+			if err != nil {
+				r.logger.
+					WithError(err).
+					WithField("source", source).
+					Warn("Couldn't parse presets ConfigMap")
+				continue
+			}
+			defs = append(defs, parsed...)
+		}
+	}
+	return defs, nil
+}
+
+func indexOf(entries []Entry, name string) int {
+	for i, entry := range entries {
+		if entry.Definition.Name == name {
+			return i
+		}
+	}
+	return -1
+}
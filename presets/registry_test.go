@@ -0,0 +1,124 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	r, err := NewRegistry("", logrus.NewEntry(logger))
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	return r
+}
+
+// newTestContext returns a context with the preset ConfigMap and
+// a counter of the ConfigMap lists.
+func newTestContext(t *testing.T, listErr error) (*kubeclient.Context, *int) {
+	t.Helper()
+
+	clientset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "presets",
+			Labels:    map[string]string{ConfigMapLabel: "true"},
+		},
+		Data: map[string]string{"app.yaml": "name: my-app\nextends: application\n"},
+	})
+
+	lists := 0
+	clientset.PrependReactor("list", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		lists++
+		return listErr != nil, nil, listErr
+	})
+
+	pool := kubeclient.NewPool()
+	if err := pool.AddStatic("fake", "fake", "", "default", kubeclient.Clients{Clientset: clientset}); err != nil {
+		t.Fatalf("AddStatic() failed: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	kctx, err := pool.Context("fake")
+	if err != nil {
+		t.Fatalf("Context() failed: %v", err)
+	}
+	return kctx, &lists
+}
+
+func TestLookupConfigMapPresets(t *testing.T) {
+	r := newTestRegistry(t)
+	kctx, lists := newTestContext(t, nil)
+
+	for i := 0; i < 3; i++ {
+		preset, err := r.Lookup(context.Background(), kctx, "my-app")
+		if err != nil {
+			t.Fatalf("Lookup() failed: %v", err)
+		}
+		if preset.Name != "my-app" {
+			t.Errorf("Lookup() = %q, want my-app", preset.Name)
+		}
+	}
+
+	if _, err := r.Lookup(context.Background(), kctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup() error = %v, want ErrNotFound", err)
+	}
+
+	// Built-in presets don't need the ConfigMaps at all.
+	if _, err := r.Lookup(context.Background(), kctx, ""); err != nil {
+		t.Errorf("Lookup() of the default preset failed: %v", err)
+	}
+
+	if *lists != 1 {
+		t.Errorf("ConfigMaps listed %d times, want once", *lists)
+	}
+}
+
+func TestLookupConfigMapPresetsForbidden(t *testing.T) {
+	r := newTestRegistry(t)
+	kctx, _ := newTestContext(t, apierrors.NewForbidden(corev1.Resource("configmaps"), "", errors.New("nope")))
+
+	entries, err := r.List(context.Background(), kctx)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(entries) != len(r.entries) {
+		t.Errorf("List() = %d presets, want only the %d built-in ones", len(entries), len(r.entries))
+	}
+
+	if _, err := r.Lookup(context.Background(), kctx, "my-app"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup() error = %v, want ErrNotFound", err)
+	}
+}
+
+// Errors other than Forbidden aren't cached.
+func TestLookupConfigMapPresetsError(t *testing.T) {
+	r := newTestRegistry(t)
+	kctx, lists := newTestContext(t, errors.New("connection refused"))
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Lookup(context.Background(), kctx, "my-app"); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Lookup() error = %v, want the list error", err)
+		}
+	}
+	if *lists != 2 {
+		t.Errorf("ConfigMaps listed %d times, want 2", *lists)
+	}
+}
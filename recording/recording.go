@@ -0,0 +1,144 @@
+// Package recording writes watch events to a JSONL file and reads them back.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/iximiuz/kexp/snapshot"
+)
+
+type EventType string
+
+const (
+	EventAdded   EventType = "added"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// Event is a line of a recording.
+type Event struct {
+	Time time.Time `json:"time"`
+
+	Context    string `json:"context"`
+	ClusterUID string `json:"clusterUID"`
+
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+
+	Type   EventType                  `json:"type"`
+	Object *unstructured.Unstructured `json:"object"`
+}
+
+func (e Event) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: e.Group, Version: e.Version, Resource: e.Resource}
+}
+
+// Recorder appends events to a file. The same object version seen
+// by several watches (or re-delivered on an informer resync) is
+// recorded only once.
+type Recorder struct {
+	mux sync.Mutex
+
+	file    *os.File
+	encoder *json.Encoder
+
+	// Object key -> last recorded resource version.
+	recorded map[string]string
+
+	includeSecretData bool
+}
+
+// NewRecorder starts (or continues) the recording in the file. The objects
+// are sanitized the same way as in snapshots - Secrets' values are redacted
+// unless includeSecretData is set.
+func NewRecorder(path string, includeSecretData bool) (*Recorder, error) {
+	// The recording may contain bits of the objects - keep it private.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open recording file: %w", err)
+	}
+
+	return &Recorder{
+		file:              file,
+		encoder:           json.NewEncoder(file),
+		recorded:          map[string]string{},
+		includeSecretData: includeSecretData,
+	}, nil
+}
+
+func (r *Recorder) Record(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	key := fmt.Sprintf(
+		"%s/%s/%s/%s",
+		event.Context,
+		event.GroupVersionResource().String(),
+		event.Object.GetNamespace(),
+		event.Object.GetName(),
+	)
+	version := event.Object.GetResourceVersion()
+	if event.Type == EventDeleted {
+		version = "deleted/" + version
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.recorded[key] == version {
+		return nil
+	}
+	if event.Type == EventDeleted {
+		// Re-deliveries of the deletion are recorded again (harmless),
+		// but the keys of deleted objects don't pile up.
+		delete(r.recorded, key)
+	} else {
+		r.recorded[key] = version
+	}
+
+	event.Object = snapshot.Sanitize(event.Object, r.includeSecretData)
+	return r.encoder.Encode(event)
+}
+
+func (r *Recorder) Close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.file.Close()
+}
+
+// Read reads all events of a recording.
+func Read(reader io.Reader) ([]Event, error) {
+	var events []Event
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // Objects can be big.
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		event := Event{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if event.Object == nil {
+			return nil, fmt.Errorf("line %d: event has no object", line)
+		}
+		events = append(events, event)
+	}
+
+	return events, scanner.Err()
+}
@@ -0,0 +1,130 @@
+package recording
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/iximiuz/kexp/snapshot"
+)
+
+func secret(resourceVersion string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"namespace":       "default",
+			"name":            "db",
+			"resourceVersion": resourceVersion,
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"password":"c2VjcmV0"}}`,
+			},
+		},
+		"data":       map[string]interface{}{"password": "c2VjcmV0"},
+		"stringData": map[string]interface{}{"user": "admin"},
+	}}
+}
+
+func record(t *testing.T, includeSecretData bool, events ...Event) (string, *Recorder) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	r, err := NewRecorder(path, includeSecretData)
+	if err != nil {
+		t.Fatalf("NewRecorder() failed: %v", err)
+	}
+
+	for _, event := range events {
+		if err := r.Record(event); err != nil {
+			t.Fatalf("Record() failed: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	return path, r
+}
+
+func readRecording(t *testing.T, path string) []Event {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	events, err := Read(file)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	return events
+}
+
+func TestRecorderRedactsSecrets(t *testing.T) {
+	event := Event{Context: "prod", Version: "v1", Resource: "secrets", Type: EventAdded, Object: secret("1")}
+	path, _ := record(t, false, event)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("recording permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	events := readRecording(t, path)
+	if len(events) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(events))
+	}
+
+	obj := events[0].Object
+	if password, _, _ := unstructured.NestedString(obj.Object, "data", "password"); password != "" {
+		t.Errorf("data.password = %q, want it redacted", password)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "stringData"); found {
+		t.Error("stringData is recorded")
+	}
+	if _, found := obj.GetAnnotations()["kubectl.kubernetes.io/last-applied-configuration"]; found {
+		t.Error("last-applied-configuration is recorded")
+	}
+	if obj.GetAnnotations()[snapshot.AnnotationRedacted] != "true" {
+		t.Error("redacted object isn't marked as such")
+	}
+
+	// The caller's object is left intact.
+	if password, _, _ := unstructured.NestedString(event.Object.Object, "data", "password"); password != "c2VjcmV0" {
+		t.Error("Record() modified the event's object")
+	}
+}
+
+func TestRecorderSecretDataOptIn(t *testing.T) {
+	path, _ := record(t, true, Event{Context: "prod", Version: "v1", Resource: "secrets", Type: EventAdded, Object: secret("1")})
+
+	events := readRecording(t, path)
+	if password, _, _ := unstructured.NestedString(events[0].Object.Object, "data", "password"); password != "c2VjcmV0" {
+		t.Errorf("data.password = %q, want the raw value", password)
+	}
+}
+
+func TestRecorderDeduplicates(t *testing.T) {
+	added := Event{Context: "prod", Version: "v1", Resource: "secrets", Type: EventAdded, Object: secret("1")}
+	updated := Event{Context: "prod", Version: "v1", Resource: "secrets", Type: EventUpdated, Object: secret("2")}
+	deleted := Event{Context: "prod", Version: "v1", Resource: "secrets", Type: EventDeleted, Object: secret("2")}
+
+	path, r := record(t, false, added, added, updated, updated, deleted)
+
+	var types []EventType
+	for _, event := range readRecording(t, path) {
+		types = append(types, event.Type)
+	}
+	if len(types) != 3 || types[0] != EventAdded || types[1] != EventUpdated || types[2] != EventDeleted {
+		t.Errorf("recorded %v, want added, updated, deleted", types)
+	}
+
+	if len(r.recorded) != 0 {
+		t.Errorf("recorded versions = %v, want the deleted object forgotten", r.recorded)
+	}
+}
@@ -0,0 +1,135 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/iximiuz/kexp/offline"
+)
+
+// Player replays a recording against in-memory clusters - one per
+// recorded context. The clusters start empty and the events are
+// applied with the original pauses between them (scaled by the speed
+// and capped - a recording may span several sessions hours apart).
+type Player struct {
+	events   []Event
+	clusters map[string]*offline.Cluster
+	logger   *logrus.Entry
+}
+
+func NewPlayer(events []Event, logger *logrus.Entry) (*Player, error) {
+	if len(events) == 0 {
+		return nil, errors.New("recording has no events")
+	}
+
+	objs := map[string][]*unstructured.Unstructured{}
+	names := map[string]map[schema.GroupVersionKind]string{}
+	for _, event := range events {
+		if names[event.Context] == nil {
+			names[event.Context] = map[schema.GroupVersionKind]string{}
+		}
+		objs[event.Context] = append(objs[event.Context], event.Object)
+		names[event.Context][event.Object.GroupVersionKind()] = event.Resource
+	}
+
+	clusters := map[string]*offline.Cluster{}
+	for name := range objs {
+		cluster, err := offline.NewCluster(nil, offline.ResourcesOf(objs[name], names[name]), nil)
+		if err != nil {
+			return nil, err
+		}
+		clusters[name] = cluster
+	}
+
+	return &Player{
+		events:   events,
+		clusters: clusters,
+		logger:   logger,
+	}, nil
+}
+
+// Clusters returns the replayed clusters by context name.
+func (p *Player) Clusters() map[string]*offline.Cluster {
+	return p.clusters
+}
+
+// ClusterUID returns the recorded UID of the context's cluster.
+func (p *Player) ClusterUID(context string) string {
+	for _, event := range p.events {
+		if event.Context == context {
+			return event.ClusterUID
+		}
+	}
+	return ""
+}
+
+// Play applies the events until the recording (or the context) is over.
+// No pause is longer than maxPause (zero means no limit).
+// If loop is set, the clusters are wiped and the replay starts over.
+func (p *Player) Play(ctx context.Context, speed float64, maxPause time.Duration, loop bool) error {
+	if speed <= 0 {
+		return errors.New("replay speed must be positive")
+	}
+	if maxPause < 0 {
+		return errors.New("max replay pause must not be negative")
+	}
+
+	for {
+		applied := map[*offline.Cluster]map[string]*unstructured.Unstructured{}
+
+		for i, event := range p.events {
+			if i > 0 {
+				pause := time.Duration(float64(event.Time.Sub(p.events[i-1].Time)) / speed)
+				if maxPause > 0 {
+					pause = min(pause, maxPause)
+				}
+				if pause > 0 {
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-time.After(pause):
+					}
+				}
+			}
+
+			cluster := p.clusters[event.Context]
+			if applied[cluster] == nil {
+				applied[cluster] = map[string]*unstructured.Unstructured{}
+			}
+			key := event.GroupVersionResource().String() + "/" + event.Object.GetNamespace() + "/" + event.Object.GetName()
+
+			var err error
+			if event.Type == EventDeleted {
+				err = cluster.Delete(event.Object)
+				delete(applied[cluster], key)
+			} else {
+				err = cluster.Apply(event.Object)
+				applied[cluster][key] = event.Object
+			}
+			if err != nil {
+				p.logger.
+					WithError(err).
+					WithField("context", event.Context).
+					WithField("event", event.Type).
+					Warn("Couldn't replay event")
+			}
+		}
+
+		if !loop {
+			p.logger.Info("Replay finished")
+			return nil
+		}
+
+		p.logger.Info("Replay finished - starting over")
+		for cluster, objs := range applied {
+			for _, obj := range objs {
+				_ = cluster.Delete(obj)
+			}
+		}
+	}
+}
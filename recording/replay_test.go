@@ -0,0 +1,59 @@
+package recording
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPlayCapsPauses(t *testing.T) {
+	configMap := func(level string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"namespace": "default", "name": "web"},
+			"data":       map[string]interface{}{"LOG_LEVEL": level},
+		}}
+	}
+
+	// Two sessions appended to the same file an hour apart.
+	start := time.Now().UTC().Add(-2 * time.Hour)
+	events := []Event{
+		{Time: start, Context: "dev", Version: "v1", Resource: "configmaps", Type: EventAdded, Object: configMap("info")},
+		{Time: start.Add(time.Hour), Context: "dev", Version: "v1", Resource: "configmaps", Type: EventUpdated, Object: configMap("debug")},
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	player, err := NewPlayer(events, logrus.NewEntry(logger))
+	if err != nil {
+		t.Fatalf("NewPlayer() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	startedAt := time.Now()
+	if err := player.Play(ctx, 1, 10*time.Millisecond, false); err != nil {
+		t.Fatalf("Play() failed: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("Play() took %v - the pause wasn't capped", time.Since(startedAt))
+	}
+}
+
+func TestPlayInvalidOptions(t *testing.T) {
+	player := &Player{}
+
+	if err := player.Play(context.Background(), 0, 0, false); err == nil {
+		t.Error("Play() with zero speed succeeded, want error")
+	}
+	if err := player.Play(context.Background(), 1, -time.Second, false); err == nil {
+		t.Error("Play() with negative max pause succeeded, want error")
+	}
+}
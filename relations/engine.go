@@ -0,0 +1,220 @@
+package relations
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// Enough to get from a Deployment to its Pods' EndpointSlices.
+	DefaultDepth = 4
+
+	MaxDepth = 10
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Engine computes graphs of related objects by (breadth-first)
+// traversing the relation rules starting from a root object.
+type Engine struct {
+	src   Source
+	rules []Rule
+	hubs  map[schema.GroupKind]bool
+}
+
+func NewEngine(src Source, rules []Rule, hubs []schema.GroupKind) *Engine {
+	e := &Engine{
+		src:   src,
+		rules: rules,
+		hubs:  map[schema.GroupKind]bool{},
+	}
+	for _, gk := range hubs {
+		e.hubs[gk] = true
+	}
+	return e
+}
+
+// Related returns the graph of objects reachable from the root
+// object in no more than depth hops.
+func (e *Engine) Related(ctx context.Context, ref ObjectRef, depth int) (*Graph, error) {
+	root, err := e.src.Get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, ErrNotFound
+	}
+
+	return e.RelatedTo(ctx, root, depth)
+}
+
+// RelatedTo is like Related but starts from an already known object.
+func (e *Engine) RelatedTo(ctx context.Context, root *unstructured.Unstructured, depth int) (*Graph, error) {
+	graph := NewGraph()
+	graph.Root = RefOf(root).ID()
+	graph.AddNode(root)
+
+	type item struct {
+		obj   *unstructured.Unstructured
+		depth int
+	}
+	queue := []item{{obj: root, depth: 0}}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		if cur.depth >= depth {
+			continue
+		}
+
+		gk := cur.obj.GroupVersionKind().GroupKind()
+		if cur.depth > 0 && e.hubs[gk] {
+			continue
+		}
+
+		visit := func(edge Edge, next *unstructured.Unstructured) {
+			graph.AddEdge(edge)
+			if graph.AddNode(next) {
+				queue = append(queue, item{obj: next, depth: cur.depth + 1})
+			}
+		}
+
+		for _, rule := range e.rules {
+			if rule.matchesFrom(gk) {
+				related, err := e.forward(ctx, rule, cur.obj)
+				if err != nil {
+					return nil, err
+				}
+				for _, obj := range related {
+					visit(NewEdge(cur.obj, obj, rule.Type), obj)
+				}
+			}
+
+			if rule.matchesTo(gk) && rule.From != AnyKind {
+				related, err := e.backward(ctx, rule, cur.obj)
+				if err != nil {
+					return nil, err
+				}
+				for _, obj := range related {
+					visit(NewEdge(obj, cur.obj, rule.Type), obj)
+				}
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// forward finds the to objects related to the given from object.
+func (e *Engine) forward(ctx context.Context, rule Rule, from *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	var candidates []*unstructured.Unstructured
+
+	if rule.Refs != nil {
+		for _, ref := range rule.Refs(from) {
+			if !rule.matchesTo(ref.GroupKind()) {
+				continue
+			}
+
+			obj, err := e.src.Get(ctx, ref)
+			if err != nil {
+				return nil, err
+			}
+			if obj != nil {
+				candidates = append(candidates, obj)
+			}
+		}
+	} else if rule.To != AnyKind {
+		objs, err := e.src.List(ctx, rule.To, e.lookupNamespace(rule, from))
+		if err != nil {
+			return nil, err
+		}
+		candidates = objs
+	}
+
+	var related []*unstructured.Unstructured
+	for _, obj := range candidates {
+		if rule.Match == nil || rule.Match(from, obj) {
+			related = append(related, obj)
+		}
+	}
+	return related, nil
+}
+
+// backward finds the from objects related to the given to object.
+func (e *Engine) backward(ctx context.Context, rule Rule, to *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	if rule.Match == nil {
+		return nil, nil
+	}
+
+	candidates, err := e.src.List(ctx, rule.From, e.lookupNamespace(rule, to))
+	if err != nil {
+		return nil, err
+	}
+
+	var related []*unstructured.Unstructured
+	for _, obj := range candidates {
+		if rule.Match(obj, to) {
+			related = append(related, obj)
+		}
+	}
+	return related, nil
+}
+
+func (e *Engine) lookupNamespace(rule Rule, known *unstructured.Unstructured) string {
+	if rule.SameNamespace {
+		return known.GetNamespace()
+	}
+	// The objects of a namespace reside in it.
+	if rule.Type == RelationNamespace && known.GroupVersionKind().GroupKind() == kindNamespace {
+		return known.GetName()
+	}
+	return ""
+}
+
+// Connect returns the graph of the given objects and the relations
+// between them (no other objects are looked up).
+func (e *Engine) Connect(objs []*unstructured.Unstructured) *Graph {
+	graph := NewGraph()
+	for _, obj := range objs {
+		graph.AddNode(obj)
+	}
+
+	for _, rule := range e.rules {
+		for _, from := range objs {
+			if !rule.matchesFrom(from.GroupVersionKind().GroupKind()) {
+				continue
+			}
+
+			refs := map[string]bool{}
+			if rule.Refs != nil {
+				for _, ref := range rule.Refs(from) {
+					refs[ref.ID()] = true
+				}
+			}
+
+			for _, to := range objs {
+				if from == to || !rule.matchesTo(to.GroupVersionKind().GroupKind()) {
+					continue
+				}
+				if rule.SameNamespace && from.GetNamespace() != to.GetNamespace() {
+					continue
+				}
+				if rule.Refs != nil && !refs[RefOf(to).ID()] {
+					continue
+				}
+				if rule.Refs == nil && rule.To == AnyKind {
+					continue
+				}
+				if rule.Match == nil || rule.Match(from, to) {
+					graph.AddEdge(NewEdge(from, to, rule.Type))
+				}
+			}
+		}
+	}
+
+	return graph
+}
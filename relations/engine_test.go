@@ -0,0 +1,262 @@
+package relations
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// memSource serves a fixed set of objects and records the list lookups.
+type memSource struct {
+	objs  []*unstructured.Unstructured
+	lists []string
+}
+
+var _ Source = (*memSource)(nil)
+
+func (s *memSource) Get(_ context.Context, ref ObjectRef) (*unstructured.Unstructured, error) {
+	for _, obj := range s.objs {
+		if RefOf(obj).ID() == ref.ID() {
+			return obj, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memSource) List(_ context.Context, gk schema.GroupKind, namespace string) ([]*unstructured.Unstructured, error) {
+	s.lists = append(s.lists, gk.String()+"@"+namespace)
+
+	var objs []*unstructured.Unstructured
+	for _, obj := range s.objs {
+		if obj.GroupVersionKind().GroupKind() == gk && (namespace == "" || obj.GetNamespace() == namespace) {
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
+
+// newTestSource returns a Deployment with its ReplicaSet and Pod, the
+// Service selecting the Pod and the ConfigMap it uses, another Pod using
+// the same ConfigMap, and a Pod in another namespace.
+func newTestSource() *memSource {
+	deploy := newObject("Deployment.apps", "default", "web", nil)
+	rs := ownedBy(newObject("ReplicaSet.apps", "default", "web-1", nil), deploy)
+	pod := withLabels(ownedBy(newObject("Pod", "default", "web-1-a", podSpec(map[string]interface{}{
+		"volumes": []interface{}{
+			map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "web-config"}},
+		},
+	})), rs), map[string]string{"app": "web"})
+
+	return &memSource{objs: []*unstructured.Unstructured{
+		newObject("Namespace", "", "default", nil),
+		newObject("Namespace", "", "other", nil),
+		deploy,
+		rs,
+		pod,
+		newObject("Service", "default", "web", map[string]interface{}{
+			"spec": map[string]interface{}{"selector": map[string]interface{}{"app": "web"}},
+		}),
+		newObject("ConfigMap", "default", "web-config", nil),
+		newObject("Pod", "default", "batch", podSpec(map[string]interface{}{
+			"containers": container(map[string]interface{}{
+				"envFrom": []interface{}{
+					map[string]interface{}{"configMapRef": map[string]interface{}{"name": "web-config"}},
+				},
+			}),
+		})),
+		withLabels(newObject("Pod", "other", "web-1-a", nil), map[string]string{"app": "web"}),
+	}}
+}
+
+func nodeIDs(graph *Graph) string {
+	var ids []string
+	for _, node := range graph.Nodes {
+		ids = append(ids, node.ID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, " ")
+}
+
+func TestRelated(t *testing.T) {
+	deployment := ObjectRef{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "default", Name: "web"}
+	configMap := ObjectRef{Version: "v1", Kind: "ConfigMap", Namespace: "default", Name: "web-config"}
+	namespace := ObjectRef{Version: "v1", Kind: "Namespace", Name: "default"}
+
+	tests := []struct {
+		name  string
+		root  ObjectRef
+		depth int
+		want  []string
+	}{
+		{
+			name:  "root only",
+			root:  deployment,
+			depth: 0,
+			want:  []string{"apps/Deployment/default/web"},
+		},
+		{
+			name:  "one hop",
+			root:  deployment,
+			depth: 1,
+			want:  []string{"apps/Deployment/default/web", "apps/ReplicaSet/default/web-1", "core/Namespace//default"},
+		},
+		{
+			// The namespace and the ConfigMap are hubs - the other
+			// pods in the namespace (or using the ConfigMap) aren't
+			// related to the Deployment through them.
+			name:  "hubs aren't traversed",
+			root:  deployment,
+			depth: MaxDepth,
+			want: []string{
+				"apps/Deployment/default/web",
+				"apps/ReplicaSet/default/web-1",
+				"core/ConfigMap/default/web-config",
+				"core/Namespace//default",
+				"core/Pod/default/web-1-a",
+				"core/Service/default/web",
+			},
+		},
+		{
+			name:  "hub root",
+			root:  configMap,
+			depth: 1,
+			want: []string{
+				"core/ConfigMap/default/web-config",
+				"core/Namespace//default",
+				"core/Pod/default/batch",
+				"core/Pod/default/web-1-a",
+			},
+		},
+		{
+			name:  "namespace root",
+			root:  namespace,
+			depth: 1,
+			want: []string{
+				"apps/Deployment/default/web",
+				"apps/ReplicaSet/default/web-1",
+				"core/ConfigMap/default/web-config",
+				"core/Namespace//default",
+				"core/Pod/default/batch",
+				"core/Pod/default/web-1-a",
+				"core/Service/default/web",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(newTestSource(), DefaultRules(), HubKinds)
+
+			graph, err := engine.Related(context.Background(), tt.root, tt.depth)
+			if err != nil {
+				t.Fatalf("Related() failed: %v", err)
+			}
+			if graph.Root != tt.root.ID() {
+				t.Errorf("Root = %q, want %q", graph.Root, tt.root.ID())
+			}
+			if got, want := nodeIDs(graph), strings.Join(tt.want, " "); got != want {
+				t.Errorf("nodes = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// The Deployment -> ReplicaSet -> Pod <- Service relations form cycles
+// (through the namespace too) - every node and edge is still added once.
+func TestRelatedCycles(t *testing.T) {
+	engine := NewEngine(newTestSource(), DefaultRules(), nil)
+
+	graph, err := engine.Related(context.Background(), ObjectRef{Version: "v1", Kind: "Service", Namespace: "default", Name: "web"}, MaxDepth)
+	if err != nil {
+		t.Fatalf("Related() failed: %v", err)
+	}
+
+	edges := map[string]bool{}
+	for _, edge := range graph.Edges {
+		if edges[edge.key()] {
+			t.Errorf("duplicate edge %s", edge.key())
+		}
+		edges[edge.key()] = true
+	}
+
+	for _, want := range []string{
+		"apps/Deployment/default/web|apps/ReplicaSet/default/web-1|owns",
+		"apps/ReplicaSet/default/web-1|core/Pod/default/web-1-a|owns",
+		"core/Service/default/web|core/Pod/default/web-1-a|selects",
+		"core/Pod/default/web-1-a|core/ConfigMap/default/web-config|references",
+		"core/Namespace//default|core/Pod/default/batch|contains",
+	} {
+		if !edges[want] {
+			t.Errorf("edge %s not found in %v", want, graph.Edges)
+		}
+	}
+
+	// The same-named Pod in the other namespace is only reachable
+	// through its namespace.
+	if _, found := graph.Node("core/Pod/other/web-1-a"); found {
+		t.Error("the Service selected a Pod in another namespace")
+	}
+}
+
+func TestRelatedNamespaceLookups(t *testing.T) {
+	src := newTestSource()
+	engine := NewEngine(src, DefaultRules(), HubKinds)
+
+	if _, err := engine.Related(context.Background(), ObjectRef{Version: "v1", Kind: "Namespace", Name: "default"}, 1); err != nil {
+		t.Fatalf("Related() failed: %v", err)
+	}
+
+	for _, lookup := range src.lists {
+		if lookup == "Pod@" {
+			t.Errorf("lookups = %v, want the namespace's pods listed in the namespace only", src.lists)
+		}
+	}
+}
+
+func TestRelatedNotFound(t *testing.T) {
+	engine := NewEngine(newTestSource(), DefaultRules(), HubKinds)
+
+	_, err := engine.Related(context.Background(), ObjectRef{Version: "v1", Kind: "Pod", Namespace: "default", Name: "nope"}, DefaultDepth)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Related() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	src := newTestSource()
+	engine := NewEngine(src, DefaultRules(), HubKinds)
+
+	// The Deployment, the ReplicaSet, and both web-1-a Pods (the other
+	// one is in another namespace but has the same labels).
+	objs := []*unstructured.Unstructured{src.objs[2], src.objs[3], src.objs[4], src.objs[8], src.objs[5]}
+	graph := engine.Connect(objs)
+
+	if len(graph.Nodes) != len(objs) {
+		t.Errorf("nodes = %v, want %d", graph.Nodes, len(objs))
+	}
+
+	var edges []string
+	for _, edge := range graph.Edges {
+		edges = append(edges, edge.key())
+	}
+	sort.Strings(edges)
+
+	want := []string{
+		"apps/Deployment/default/web|apps/ReplicaSet/default/web-1|owns",
+		"apps/ReplicaSet/default/web-1|core/Pod/default/web-1-a|owns",
+		"core/Service/default/web|core/Pod/default/web-1-a|selects",
+	}
+	if strings.Join(edges, " ") != strings.Join(want, " ") {
+		t.Errorf("edges = %v, want %v", edges, want)
+	}
+
+	// No lookups - only the given objects are connected.
+	if len(src.lists) != 0 {
+		t.Errorf("Connect() listed %v", src.lists)
+	}
+}
@@ -0,0 +1,230 @@
+package relations
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type ExportFormat string
+
+const (
+	FormatDOT     ExportFormat = "dot"
+	FormatMermaid ExportFormat = "mermaid"
+	FormatGraphML ExportFormat = "graphml"
+
+	// Human-readable tree - for terminals.
+	FormatTree ExportFormat = "tree"
+)
+
+var ExportFormats = []ExportFormat{FormatDOT, FormatMermaid, FormatGraphML, FormatTree}
+
+func ParseExportFormat(s string) (ExportFormat, error) {
+	for _, f := range ExportFormats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q", s)
+}
+
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case FormatGraphML:
+		return "application/graphml+xml"
+	case FormatMermaid:
+		return "text/vnd.mermaid"
+	case FormatTree:
+		return "text/plain"
+	default:
+		return "text/vnd.graphviz"
+	}
+}
+
+// Export writes the graph in the given format. Nodes are labeled
+// with kind/name and edges with the relation type.
+func Export(w io.Writer, graph *Graph, format ExportFormat) error {
+	switch format {
+	case FormatDOT:
+		return exportDOT(w, graph)
+	case FormatMermaid:
+		return exportMermaid(w, graph)
+	case FormatGraphML:
+		return exportGraphML(w, graph)
+	case FormatTree:
+		return exportTree(w, graph)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+func nodeLabel(node Node) string {
+	return node.Kind + "/" + node.Name
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+func exportDOT(w io.Writer, graph *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph kexp {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=box];")
+
+	for _, node := range graph.Nodes {
+		attrs := "label=" + dotQuote(nodeLabel(node))
+		if node.Namespace != "" {
+			attrs += ", tooltip=" + dotQuote("namespace: "+node.Namespace)
+		}
+		if node.ID == graph.Root {
+			attrs += ", style=bold"
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(node.ID), attrs)
+	}
+
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [label=%s];\n",
+			dotQuote(edge.From), dotQuote(edge.To), dotQuote(string(edge.Type)))
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// Mermaid renders labels as HTML.
+var mermaidEscaper = strings.NewReplacer(
+	`"`, "#quot;",
+	"|", "#124;",
+	"<", "#lt;",
+	">", "#gt;",
+	"&", "#amp;",
+	"\n", " ",
+)
+
+func exportMermaid(w io.Writer, graph *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	// Object IDs contain characters Mermaid doesn't allow in node IDs.
+	ids := map[string]string{}
+	for i, node := range graph.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	fmt.Fprintln(bw, "graph LR")
+
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(bw, "  %s[\"%s\"]\n", ids[node.ID], mermaidEscaper.Replace(nodeLabel(node)))
+	}
+
+	for _, edge := range graph.Edges {
+		from, to := ids[edge.From], ids[edge.To]
+		if from == "" || to == "" {
+			continue
+		}
+		fmt.Fprintf(bw, "  %s -->|%s| %s\n", from, mermaidEscaper.Replace(string(edge.Type)), to)
+	}
+
+	if root, found := ids[graph.Root]; found {
+		fmt.Fprintf(bw, "  style %s stroke-width:3px\n", root)
+	}
+
+	return bw.Flush()
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func exportGraphML(w io.Writer, graph *Graph) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "group", For: "node", AttrName: "group", AttrType: "string"},
+			{ID: "version", For: "node", AttrName: "version", AttrType: "string"},
+			{ID: "kind", For: "node", AttrName: "kind", AttrType: "string"},
+			{ID: "namespace", For: "node", AttrName: "namespace", AttrType: "string"},
+			{ID: "name", For: "node", AttrName: "name", AttrType: "string"},
+			{ID: "root", For: "node", AttrName: "root", AttrType: "boolean"},
+			{ID: "type", For: "edge", AttrName: "label", AttrType: "string"},
+		},
+		Graph: graphMLGraph{
+			ID:          "kexp",
+			EdgeDefault: "directed",
+		},
+	}
+
+	for _, node := range graph.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: nodeLabel(node)},
+				{Key: "group", Value: node.Group},
+				{Key: "version", Value: node.Version},
+				{Key: "kind", Value: node.Kind},
+				{Key: "namespace", Value: node.Namespace},
+				{Key: "name", Value: node.Name},
+				{Key: "root", Value: fmt.Sprint(node.ID == graph.Root)},
+			},
+		})
+	}
+
+	for _, edge := range graph.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: edge.From,
+			Target: edge.To,
+			Data:   []graphMLData{{Key: "type", Value: string(edge.Type)}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,67 @@
+package relations
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// newExportTestGraph returns a small graph with a label that needs
+// escaping in every format and a node that isn't connected to the root.
+func newExportTestGraph() *Graph {
+	deploy := newObject("Deployment.apps", "default", "web", nil)
+	rs := newObject("ReplicaSet.apps", "default", "web-1", nil)
+	ns := newObject("Namespace", "", "default", nil)
+	odd := newObject("ConfigMap", "default", "say \"hi\" <b>&\nbye", nil)
+	node := newObject("Node", "", "node-1", nil)
+
+	graph := NewGraph()
+	graph.Root = RefOf(deploy).ID()
+	for _, obj := range []*unstructured.Unstructured{deploy, rs, ns, odd, node} {
+		graph.AddNode(obj)
+	}
+
+	graph.AddEdge(NewEdge(deploy, rs, RelationOwnership))
+	graph.AddEdge(NewEdge(ns, deploy, RelationNamespace))
+	graph.AddEdge(NewEdge(rs, odd, RelationType("uses \"it\" | <&>")))
+
+	return graph
+}
+
+func TestExport(t *testing.T) {
+	for _, format := range ExportFormats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Export(&buf, newExportTestGraph(), format); err != nil {
+				t.Fatalf("Export() failed: %v", err)
+			}
+
+			golden := filepath.Join("testdata", "export."+string(format))
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("Export() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	if err := Export(&bytes.Buffer{}, NewGraph(), "png"); err == nil {
+		t.Error("Export() succeeded, want error")
+	}
+}
@@ -0,0 +1,226 @@
+package relations
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type RelationType string
+
+const (
+	// Owner -> dependent (metadata.ownerReferences).
+	RelationOwnership RelationType = "owns"
+
+	// Selector holder -> selected object (e.g., Service -> Pod).
+	RelationSelection RelationType = "selects"
+
+	// Referrer -> referenced object (e.g., Pod -> ConfigMap).
+	RelationReference RelationType = "references"
+
+	// Pod -> Node.
+	RelationScheduling RelationType = "runs-on"
+
+	// Event -> involved object.
+	RelationEvent RelationType = "describes"
+
+	// Namespace -> namespaced object.
+	RelationNamespace RelationType = "contains"
+)
+
+// ObjectRef identifies a Kubernetes object.
+type ObjectRef struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func RefOf(obj *unstructured.Unstructured) ObjectRef {
+	gvk := obj.GroupVersionKind()
+	return ObjectRef{
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+}
+
+func (r ObjectRef) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: r.Group, Version: r.Version, Kind: r.Kind}
+}
+
+func (r ObjectRef) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: r.Group, Kind: r.Kind}
+}
+
+// ID is unique within a cluster and stable across versions of the object.
+func (r ObjectRef) ID() string {
+	group := r.Group
+	if group == "" {
+		group = "core"
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", group, r.Kind, r.Namespace, r.Name)
+}
+
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return r.Kind + "/" + r.Name
+	}
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+type Node struct {
+	ID string `json:"id"`
+	ObjectRef
+	UID string `json:"uid"`
+}
+
+type Edge struct {
+	From string       `json:"from"`
+	To   string       `json:"to"`
+	Type RelationType `json:"type"`
+}
+
+func NewEdge(from, to *unstructured.Unstructured, typ RelationType) Edge {
+	return Edge{
+		From: RefOf(from).ID(),
+		To:   RefOf(to).ID(),
+		Type: typ,
+	}
+}
+
+func (e Edge) key() string {
+	return e.From + "|" + e.To + "|" + string(e.Type)
+}
+
+type Graph struct {
+	Root  string `json:"root"`
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+
+	nodeIndex map[string]int
+	edgeIndex map[string]struct{}
+	objects   map[string]*unstructured.Unstructured
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		Nodes:     []Node{},
+		Edges:     []Edge{},
+		nodeIndex: map[string]int{},
+		edgeIndex: map[string]struct{}{},
+		objects:   map[string]*unstructured.Unstructured{},
+	}
+}
+
+// AddNode returns true if the node hasn't been in the graph before.
+func (g *Graph) AddNode(obj *unstructured.Unstructured) bool {
+	ref := RefOf(obj)
+	if _, found := g.nodeIndex[ref.ID()]; found {
+		return false
+	}
+
+	g.objects[ref.ID()] = obj
+	g.nodeIndex[ref.ID()] = len(g.Nodes)
+	g.Nodes = append(g.Nodes, Node{
+		ID:        ref.ID(),
+		ObjectRef: ref,
+		UID:       string(obj.GetUID()),
+	})
+	return true
+}
+
+// AddEdge returns true if the edge hasn't been in the graph before.
+func (g *Graph) AddEdge(edge Edge) bool {
+	if _, found := g.edgeIndex[edge.key()]; found {
+		return false
+	}
+
+	g.edgeIndex[edge.key()] = struct{}{}
+	g.Edges = append(g.Edges, edge)
+	return true
+}
+
+func (g *Graph) Node(id string) (Node, bool) {
+	if i, found := g.nodeIndex[id]; found {
+		return g.Nodes[i], true
+	}
+	return Node{}, false
+}
+
+// Object returns the object behind the node (if known).
+func (g *Graph) Object(id string) (*unstructured.Unstructured, bool) {
+	obj, found := g.objects[id]
+	return obj, found
+}
+
+// GraphDiff describes how to turn one graph into another.
+type GraphDiff struct {
+	Nodes NodesDiff `json:"nodes"`
+	Edges EdgesDiff `json:"edges"`
+}
+
+type NodesDiff struct {
+	Added   []Node `json:"added"`
+	Updated []Node `json:"updated"`
+	Removed []Node `json:"removed"`
+}
+
+type EdgesDiff struct {
+	Added   []Edge `json:"added"`
+	Removed []Edge `json:"removed"`
+}
+
+func (d GraphDiff) IsEmpty() bool {
+	return len(d.Nodes.Added) == 0 &&
+		len(d.Nodes.Updated) == 0 &&
+		len(d.Nodes.Removed) == 0 &&
+		len(d.Edges.Added) == 0 &&
+		len(d.Edges.Removed) == 0
+}
+
+// Diff compares two graphs. Nodes are considered updated
+// if the resource versions of their objects differ.
+func Diff(prev, next *Graph) GraphDiff {
+	diff := GraphDiff{
+		Nodes: NodesDiff{Added: []Node{}, Updated: []Node{}, Removed: []Node{}},
+		Edges: EdgesDiff{Added: []Edge{}, Removed: []Edge{}},
+	}
+
+	for _, node := range next.Nodes {
+		if _, found := prev.Node(node.ID); !found {
+			diff.Nodes.Added = append(diff.Nodes.Added, node)
+			continue
+		}
+
+		prevObj, _ := prev.Object(node.ID)
+		nextObj, _ := next.Object(node.ID)
+		if prevObj.GetResourceVersion() != nextObj.GetResourceVersion() {
+			diff.Nodes.Updated = append(diff.Nodes.Updated, node)
+		}
+	}
+
+	for _, node := range prev.Nodes {
+		if _, found := next.Node(node.ID); !found {
+			diff.Nodes.Removed = append(diff.Nodes.Removed, node)
+		}
+	}
+
+	for _, edge := range next.Edges {
+		if _, found := prev.edgeIndex[edge.key()]; !found {
+			diff.Edges.Added = append(diff.Edges.Added, edge)
+		}
+	}
+
+	for _, edge := range prev.Edges {
+		if _, found := next.edgeIndex[edge.key()]; !found {
+			diff.Edges.Removed = append(diff.Edges.Removed, edge)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,101 @@
+package relations
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	PresetRelated     = "related"
+	PresetApplication = "application"
+)
+
+// Preset is a named way of computing a graph of related objects.
+type Preset struct {
+	Name string
+
+	// Only these relations are traversed.
+	Rules []Rule
+
+	// See HubKinds.
+	Hubs []schema.GroupKind
+}
+
+func (p Preset) Engine(src Source) *Engine {
+	return NewEngine(src, p.Rules, p.Hubs)
+}
+
+// Kinds returns all kinds the preset's rules can reach (except AnyKind).
+func (p Preset) Kinds() []schema.GroupKind {
+	seen := map[schema.GroupKind]bool{}
+	kinds := []schema.GroupKind{}
+
+	for _, rule := range p.Rules {
+		for _, gk := range []schema.GroupKind{rule.From, rule.To} {
+			if gk != AnyKind && !seen[gk] {
+				seen[gk] = true
+				kinds = append(kinds, gk)
+			}
+		}
+	}
+
+	return kinds
+}
+
+// Restrict returns a copy of the preset traversing only
+// the relations between the given kinds.
+func (p Preset) Restrict(name string, kinds []schema.GroupKind) Preset {
+	allowed := map[schema.GroupKind]bool{}
+	for _, gk := range kinds {
+		allowed[gk] = true
+	}
+
+	restricted := Preset{Name: name, Hubs: p.Hubs}
+	for _, rule := range p.Rules {
+		if allowed[rule.From] && allowed[rule.To] {
+			restricted.Rules = append(restricted.Rules, rule)
+		}
+	}
+	return restricted
+}
+
+// ApplicationKinds are the kinds the "application" preset is made of.
+var ApplicationKinds = []schema.GroupKind{
+	kindConfigMap,
+	kindCronJob,
+	kindDaemonSet,
+	kindDeployment,
+	kindJob,
+	kindPod,
+	kindReplicaSet,
+	kindReplicationController,
+	kindSecret,
+	kindService,
+	kindServiceAccount,
+	kindStatefulSet,
+}
+
+func BuiltinPresets() []Preset {
+	related := Preset{
+		Name:  PresetRelated,
+		Rules: DefaultRules(),
+		Hubs:  HubKinds,
+	}
+
+	return []Preset{
+		related,
+		related.Restrict(PresetApplication, ApplicationKinds),
+	}
+}
+
+func LookupPreset(name string) (Preset, bool) {
+	if name == "" {
+		name = PresetRelated
+	}
+
+	for _, p := range BuiltinPresets() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
@@ -0,0 +1,301 @@
+package relations
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	kindConfigMap             = schema.GroupKind{Kind: "ConfigMap"}
+	kindEndpoints             = schema.GroupKind{Kind: "Endpoints"}
+	kindEvent                 = schema.GroupKind{Kind: "Event"}
+	kindNamespace             = schema.GroupKind{Kind: "Namespace"}
+	kindNode                  = schema.GroupKind{Kind: "Node"}
+	kindPod                   = schema.GroupKind{Kind: "Pod"}
+	kindReplicationController = schema.GroupKind{Kind: "ReplicationController"}
+	kindSecret                = schema.GroupKind{Kind: "Secret"}
+	kindService               = schema.GroupKind{Kind: "Service"}
+	kindServiceAccount        = schema.GroupKind{Kind: "ServiceAccount"}
+
+	kindControllerRevision = schema.GroupKind{Group: "apps", Kind: "ControllerRevision"}
+	kindDaemonSet          = schema.GroupKind{Group: "apps", Kind: "DaemonSet"}
+	kindDeployment         = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	kindReplicaSet         = schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}
+	kindStatefulSet        = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+
+	kindCronJob = schema.GroupKind{Group: "batch", Kind: "CronJob"}
+	kindJob     = schema.GroupKind{Group: "batch", Kind: "Job"}
+
+	kindEndpointSlice = schema.GroupKind{Group: "discovery.k8s.io", Kind: "EndpointSlice"}
+)
+
+// AnyKind in a rule matches objects of every kind.
+var AnyKind = schema.GroupKind{Kind: "*"}
+
+// Rule describes a direct relation between objects of two kinds.
+// Rules are traversed in both directions.
+type Rule struct {
+	Type RelationType
+
+	From schema.GroupKind
+	To   schema.GroupKind
+
+	// Both objects reside in the same namespace - candidates
+	// can be looked up in the known object's namespace only.
+	SameNamespace bool
+
+	// Match reports whether the from object relates to the to object.
+	Match func(from, to *unstructured.Unstructured) bool
+
+	// Refs (optional) resolves the to objects directly from the from
+	// object. Required for rules with To set to AnyKind.
+	Refs func(from *unstructured.Unstructured) []ObjectRef
+}
+
+func (r Rule) matchesFrom(gk schema.GroupKind) bool {
+	return r.From == AnyKind || r.From == gk
+}
+
+func (r Rule) matchesTo(gk schema.GroupKind) bool {
+	return r.To == AnyKind || r.To == gk
+}
+
+// HubKinds are related to too many objects to be traversed through.
+// Their relations are expanded only when such an object is the root.
+var HubKinds = []schema.GroupKind{
+	kindConfigMap,
+	kindEvent,
+	kindNamespace,
+	kindNode,
+	kindSecret,
+	kindServiceAccount,
+}
+
+// DefaultRules is the Go port of the relations the UI has been using.
+func DefaultRules() []Rule {
+	rules := []Rule{}
+
+	for _, pair := range [][2]schema.GroupKind{
+		{kindCronJob, kindJob},
+		{kindDaemonSet, kindControllerRevision},
+		{kindDaemonSet, kindPod},
+		{kindDeployment, kindReplicaSet},
+		{kindJob, kindPod},
+		{kindReplicaSet, kindPod},
+		{kindReplicationController, kindPod},
+		{kindService, kindEndpointSlice},
+		{kindStatefulSet, kindControllerRevision},
+		{kindStatefulSet, kindPod},
+	} {
+		rules = append(rules, OwnershipRule(pair[0], pair[1]))
+	}
+
+	rules = append(rules,
+		Rule{
+			Type:          RelationSelection,
+			From:          kindService,
+			To:            kindPod,
+			SameNamespace: true,
+			Match: func(svc, pod *unstructured.Unstructured) bool {
+				selector, _, _ := unstructured.NestedStringMap(svc.Object, "spec", "selector")
+				return SelectorMatches(selector, pod)
+			},
+		},
+		Rule{
+			Type:          RelationReference,
+			From:          kindService,
+			To:            kindEndpoints,
+			SameNamespace: true,
+			Match: func(svc, ep *unstructured.Unstructured) bool {
+				return svc.GetName() == ep.GetName()
+			},
+		},
+		Rule{
+			Type:          RelationReference,
+			From:          kindPod,
+			To:            kindConfigMap,
+			SameNamespace: true,
+			Match: func(pod, cm *unstructured.Unstructured) bool {
+				return podReferences(pod, "configMap", cm.GetName())
+			},
+		},
+		Rule{
+			Type:          RelationReference,
+			From:          kindPod,
+			To:            kindSecret,
+			SameNamespace: true,
+			Match: func(pod, sec *unstructured.Unstructured) bool {
+				return podReferences(pod, "secret", sec.GetName())
+			},
+		},
+		Rule{
+			Type:          RelationReference,
+			From:          kindPod,
+			To:            kindServiceAccount,
+			SameNamespace: true,
+			Match: func(pod, sa *unstructured.Unstructured) bool {
+				name, _, _ := unstructured.NestedString(pod.Object, "spec", "serviceAccountName")
+				if name == "" {
+					name = "default"
+				}
+				return name == sa.GetName()
+			},
+		},
+		Rule{
+			Type: RelationScheduling,
+			From: kindPod,
+			To:   kindNode,
+			Match: func(pod, node *unstructured.Unstructured) bool {
+				name, _, _ := unstructured.NestedString(pod.Object, "spec", "nodeName")
+				return name != "" && name == node.GetName()
+			},
+		},
+		Rule{
+			Type:          RelationEvent,
+			From:          kindEvent,
+			To:            AnyKind,
+			SameNamespace: true,
+			Match: func(evt, obj *unstructured.Unstructured) bool {
+				uid, _, _ := unstructured.NestedString(evt.Object, "involvedObject", "uid")
+				return uid != "" && uid == string(obj.GetUID())
+			},
+			Refs: func(evt *unstructured.Unstructured) []ObjectRef {
+				obj, _, _ := unstructured.NestedStringMap(evt.Object, "involvedObject")
+				gv, err := schema.ParseGroupVersion(obj["apiVersion"])
+				if err != nil || obj["kind"] == "" || obj["name"] == "" {
+					return nil
+				}
+				return []ObjectRef{{
+					Group:     gv.Group,
+					Version:   gv.Version,
+					Kind:      obj["kind"],
+					Namespace: obj["namespace"],
+					Name:      obj["name"],
+				}}
+			},
+		},
+	)
+
+	for _, kind := range []schema.GroupKind{
+		kindConfigMap,
+		kindCronJob,
+		kindDaemonSet,
+		kindDeployment,
+		kindJob,
+		kindPod,
+		kindReplicaSet,
+		kindReplicationController,
+		kindSecret,
+		kindService,
+		kindServiceAccount,
+		kindStatefulSet,
+	} {
+		rules = append(rules, NamespaceRule(kind))
+	}
+
+	return rules
+}
+
+// OwnershipRule relates owners to their dependents of the given kinds.
+func OwnershipRule(owner, dependent schema.GroupKind) Rule {
+	return Rule{
+		Type:          RelationOwnership,
+		From:          owner,
+		To:            dependent,
+		SameNamespace: true,
+		Match: func(owner, dependent *unstructured.Unstructured) bool {
+			return IsOwnedBy(dependent, owner)
+		},
+	}
+}
+
+// NamespaceRule relates namespaces to the objects of the given kind.
+func NamespaceRule(kind schema.GroupKind) Rule {
+	return Rule{
+		Type: RelationNamespace,
+		From: kindNamespace,
+		To:   kind,
+		Match: func(ns, obj *unstructured.Unstructured) bool {
+			return ns.GetName() == obj.GetNamespace()
+		},
+	}
+}
+
+func IsOwnedBy(obj, owner *unstructured.Unstructured) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectorMatches treats an empty selector as matching nothing
+// (as Services do).
+func SelectorMatches(selector map[string]string, obj *unstructured.Unstructured) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	return labels.SelectorFromSet(selector).Matches(labels.Set(obj.GetLabels()))
+}
+
+// podReferences reports whether the pod uses a configMap or a secret
+// (depending on the source) with the given name in env, envFrom, or volumes.
+func podReferences(pod *unstructured.Unstructured, source string, name string) bool {
+	refField, keyRefField, volumeField, volumeNameField := "configMapRef", "configMapKeyRef", "configMap", "name"
+	if source == "secret" {
+		refField, keyRefField, volumeField, volumeNameField = "secretRef", "secretKeyRef", "secret", "secretName"
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", field)
+		for _, c := range containers {
+			c, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			envFrom, _, _ := unstructured.NestedSlice(c, "envFrom")
+			for _, e := range envFrom {
+				if e, ok := e.(map[string]interface{}); ok {
+					if n, _, _ := unstructured.NestedString(e, refField, "name"); n == name {
+						return true
+					}
+				}
+			}
+
+			env, _, _ := unstructured.NestedSlice(c, "env")
+			for _, e := range env {
+				if e, ok := e.(map[string]interface{}); ok {
+					if n, _, _ := unstructured.NestedString(e, "valueFrom", keyRefField, "name"); n == name {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+	for _, v := range volumes {
+		v, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if n, _, _ := unstructured.NestedString(v, volumeField, volumeNameField); n == name {
+			return true
+		}
+
+		sources, _, _ := unstructured.NestedSlice(v, "projected", "sources")
+		for _, s := range sources {
+			if s, ok := s.(map[string]interface{}); ok {
+				if n, _, _ := unstructured.NestedString(s, volumeField, "name"); n == name {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
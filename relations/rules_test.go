@@ -0,0 +1,312 @@
+package relations
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newObject builds an object of the kind (given as "Kind[.group]") with
+// the extra fields merged into it.
+func newObject(kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	gk := schema.ParseGroupKind(kind)
+	gv := schema.GroupVersion{Group: gk.Group, Version: "v1"}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for k, v := range fields {
+		obj.Object[k] = v
+	}
+	obj.SetAPIVersion(gv.String())
+	obj.SetKind(gk.Kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetUID(types.UID("uid-" + kind + "-" + name))
+	return obj
+}
+
+func ownedBy(obj, owner *unstructured.Unstructured) *unstructured.Unstructured {
+	obj.SetOwnerReferences(append(obj.GetOwnerReferences(), metav1.OwnerReference{
+		APIVersion: owner.GetAPIVersion(),
+		Kind:       owner.GetKind(),
+		Name:       owner.GetName(),
+		UID:        owner.GetUID(),
+	}))
+	return obj
+}
+
+func withLabels(obj *unstructured.Unstructured, labels map[string]string) *unstructured.Unstructured {
+	obj.SetLabels(labels)
+	return obj
+}
+
+func podSpec(spec map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"spec": spec}
+}
+
+func container(fields map[string]interface{}) []interface{} {
+	c := map[string]interface{}{"name": "app"}
+	for k, v := range fields {
+		c[k] = v
+	}
+	return []interface{}{c}
+}
+
+// findRule returns the first default rule of the type between the kinds.
+func findRule(t *testing.T, typ RelationType, from, to schema.GroupKind) Rule {
+	t.Helper()
+	for _, rule := range DefaultRules() {
+		if rule.Type == typ && rule.From == from && rule.To == to {
+			return rule
+		}
+	}
+	t.Fatalf("no %s rule from %s to %s", typ, from, to)
+	return Rule{}
+}
+
+func TestOwnershipRule(t *testing.T) {
+	rule := OwnershipRule(kindDeployment, kindReplicaSet)
+	deploy := newObject("Deployment.apps", "default", "web", nil)
+
+	tests := []struct {
+		name      string
+		dependent *unstructured.Unstructured
+		want      bool
+	}{
+		{"owned", ownedBy(newObject("ReplicaSet.apps", "default", "web-1", nil), deploy), true},
+		{"owned by another", ownedBy(newObject("ReplicaSet.apps", "default", "web-1", nil), newObject("Deployment.apps", "default", "api", nil)), false},
+		{"no owners", newObject("ReplicaSet.apps", "default", "web-1", nil), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Match(deploy, tt.dependent); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !rule.SameNamespace {
+		t.Error("ownership rules must be namespace-local")
+	}
+}
+
+func TestServiceSelection(t *testing.T) {
+	rule := findRule(t, RelationSelection, kindService, kindPod)
+	service := func(selector map[string]interface{}) *unstructured.Unstructured {
+		return newObject("Service", "default", "web", map[string]interface{}{
+			"spec": map[string]interface{}{"selector": selector},
+		})
+	}
+	pod := withLabels(newObject("Pod", "default", "web-0", nil), map[string]string{"app": "web", "tier": "frontend"})
+
+	tests := []struct {
+		name string
+		svc  *unstructured.Unstructured
+		want bool
+	}{
+		{"subset of the labels", service(map[string]interface{}{"app": "web"}), true},
+		{"all labels", service(map[string]interface{}{"app": "web", "tier": "frontend"}), true},
+		{"different value", service(map[string]interface{}{"app": "api"}), false},
+		{"extra key", service(map[string]interface{}{"app": "web", "env": "prod"}), false},
+		{"empty selector", service(map[string]interface{}{}), false},
+		{"no selector", newObject("Service", "default", "web", nil), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Match(tt.svc, pod); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodReferences(t *testing.T) {
+	configMapRule := findRule(t, RelationReference, kindPod, kindConfigMap)
+	secretRule := findRule(t, RelationReference, kindPod, kindSecret)
+
+	configMap := newObject("ConfigMap", "default", "web-config", nil)
+	secret := newObject("Secret", "default", "web-creds", nil)
+
+	tests := []struct {
+		name          string
+		spec          map[string]interface{}
+		wantConfigMap bool
+		wantSecret    bool
+	}{
+		{
+			name: "envFrom",
+			spec: map[string]interface{}{"containers": container(map[string]interface{}{
+				"envFrom": []interface{}{
+					map[string]interface{}{"configMapRef": map[string]interface{}{"name": "web-config"}},
+					map[string]interface{}{"secretRef": map[string]interface{}{"name": "web-creds"}},
+				},
+			})},
+			wantConfigMap: true,
+			wantSecret:    true,
+		},
+		{
+			name: "env key refs in init containers",
+			spec: map[string]interface{}{"initContainers": container(map[string]interface{}{
+				"env": []interface{}{
+					map[string]interface{}{"name": "A", "valueFrom": map[string]interface{}{
+						"configMapKeyRef": map[string]interface{}{"name": "web-config", "key": "a"},
+					}},
+					map[string]interface{}{"name": "B", "valueFrom": map[string]interface{}{
+						"secretKeyRef": map[string]interface{}{"name": "web-creds", "key": "b"},
+					}},
+				},
+			})},
+			wantConfigMap: true,
+			wantSecret:    true,
+		},
+		{
+			name: "volumes",
+			spec: map[string]interface{}{"volumes": []interface{}{
+				map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "web-config"}},
+				map[string]interface{}{"name": "creds", "secret": map[string]interface{}{"secretName": "web-creds"}},
+			}},
+			wantConfigMap: true,
+			wantSecret:    true,
+		},
+		{
+			name: "projected volume",
+			spec: map[string]interface{}{"volumes": []interface{}{
+				map[string]interface{}{"name": "all", "projected": map[string]interface{}{"sources": []interface{}{
+					map[string]interface{}{"secret": map[string]interface{}{"name": "web-creds"}},
+				}}},
+			}},
+			wantSecret: true,
+		},
+		{
+			// Secret volumes are named by secretName, not name.
+			name: "secret volume by name",
+			spec: map[string]interface{}{"volumes": []interface{}{
+				map[string]interface{}{"name": "creds", "secret": map[string]interface{}{"name": "web-creds"}},
+			}},
+		},
+		{
+			name: "other objects",
+			spec: map[string]interface{}{
+				"containers": container(map[string]interface{}{
+					"envFrom": []interface{}{
+						map[string]interface{}{"configMapRef": map[string]interface{}{"name": "api-config"}},
+					},
+				}),
+				"volumes": []interface{}{
+					map[string]interface{}{"name": "data", "emptyDir": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := newObject("Pod", "default", "web-0", podSpec(tt.spec))
+			if got := configMapRule.Match(pod, configMap); got != tt.wantConfigMap {
+				t.Errorf("ConfigMap Match() = %v, want %v", got, tt.wantConfigMap)
+			}
+			if got := secretRule.Match(pod, secret); got != tt.wantSecret {
+				t.Errorf("Secret Match() = %v, want %v", got, tt.wantSecret)
+			}
+		})
+	}
+}
+
+func TestPodServiceAccountAndNode(t *testing.T) {
+	saRule := findRule(t, RelationReference, kindPod, kindServiceAccount)
+	nodeRule := findRule(t, RelationScheduling, kindPod, kindNode)
+
+	defaultSA := newObject("ServiceAccount", "default", "default", nil)
+	webSA := newObject("ServiceAccount", "default", "web", nil)
+	node := newObject("Node", "", "node-1", nil)
+
+	unscheduled := newObject("Pod", "default", "web-0", nil)
+	scheduled := newObject("Pod", "default", "web-1", podSpec(map[string]interface{}{
+		"serviceAccountName": "web",
+		"nodeName":           "node-1",
+	}))
+
+	if !saRule.Match(unscheduled, defaultSA) || saRule.Match(unscheduled, webSA) {
+		t.Error("a pod without serviceAccountName must use the default service account")
+	}
+	if !saRule.Match(scheduled, webSA) || saRule.Match(scheduled, defaultSA) {
+		t.Error("a pod must use its serviceAccountName")
+	}
+	if nodeRule.Match(unscheduled, node) || !nodeRule.Match(scheduled, node) {
+		t.Error("only scheduled pods run on nodes")
+	}
+}
+
+func TestNamespaceRule(t *testing.T) {
+	rule := NamespaceRule(kindPod)
+	ns := newObject("Namespace", "", "default", nil)
+
+	if !rule.Match(ns, newObject("Pod", "default", "web-0", nil)) {
+		t.Error("Match() = false for a pod in the namespace")
+	}
+	if rule.Match(ns, newObject("Pod", "kube-system", "dns-0", nil)) {
+		t.Error("Match() = true for a pod in another namespace")
+	}
+	if rule.SameNamespace {
+		t.Error("namespaces aren't namespaced")
+	}
+}
+
+func TestEventRule(t *testing.T) {
+	rule := findRule(t, RelationEvent, kindEvent, AnyKind)
+	event := func(involved map[string]interface{}) *unstructured.Unstructured {
+		return newObject("Event", "default", "web.1", map[string]interface{}{"involvedObject": involved})
+	}
+
+	tests := []struct {
+		name  string
+		event *unstructured.Unstructured
+		want  []ObjectRef
+	}{
+		{
+			name: "namespaced",
+			event: event(map[string]interface{}{
+				"apiVersion": "apps/v1", "kind": "Deployment", "namespace": "default", "name": "web", "uid": "uid-Deployment.apps-web",
+			}),
+			want: []ObjectRef{{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "default", Name: "web"}},
+		},
+		{
+			name:  "core group",
+			event: event(map[string]interface{}{"apiVersion": "v1", "kind": "Node", "name": "node-1"}),
+			want:  []ObjectRef{{Version: "v1", Kind: "Node", Name: "node-1"}},
+		},
+		{
+			name:  "no name",
+			event: event(map[string]interface{}{"apiVersion": "v1", "kind": "Pod"}),
+		},
+		{
+			name:  "malformed apiVersion",
+			event: event(map[string]interface{}{"apiVersion": "a/b/c", "kind": "Pod", "name": "web-0"}),
+		},
+		{
+			name:  "no involved object",
+			event: newObject("Event", "default", "web.1", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Refs(tt.event); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Refs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	deploy := newObject("Deployment.apps", "default", "web", nil)
+	if !rule.Match(tests[0].event, deploy) {
+		t.Error("Match() = false for the involved object")
+	}
+	if rule.Match(tests[1].event, deploy) {
+		t.Error("Match() = true for another object")
+	}
+}
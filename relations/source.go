@@ -0,0 +1,219 @@
+package relations
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+// Source is where the engine takes the objects from.
+type Source interface {
+	// Get returns nil (and no error) if the object doesn't exist.
+	Get(ctx context.Context, ref ObjectRef) (*unstructured.Unstructured, error)
+
+	// List returns all objects of the kind in the namespace
+	// (or in all namespaces if the namespace is empty).
+	List(ctx context.Context, gk schema.GroupKind, namespace string) ([]*unstructured.Unstructured, error)
+}
+
+// ClientSource reads objects from the Kubernetes API. Lists are
+// memoized, so a source instance should be used for a single query.
+// Kinds that aren't served by the cluster (or that the user has no
+// access to) are treated as empty. Suits one-off queries - long-running
+// servers should use CacheSource instead.
+type ClientSource struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+
+	mux   sync.Mutex
+	lists map[string][]*unstructured.Unstructured
+}
+
+var _ Source = (*ClientSource)(nil)
+
+func NewClientSource(kctx *kubeclient.Context) (*ClientSource, error) {
+	client, err := kctx.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	discoveryClient, err := kctx.DiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientSource{
+		client: client,
+		mapper: restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient),
+		lists:  map[string][]*unstructured.Unstructured{},
+	}, nil
+}
+
+// Mapper exposes the source's REST mapper (e.g., to resolve resources to kinds).
+func (s *ClientSource) Mapper() meta.RESTMapper {
+	return s.mapper
+}
+
+func (s *ClientSource) Get(ctx context.Context, ref ObjectRef) (*unstructured.Unstructured, error) {
+	mapping, err := s.mapper.RESTMapping(ref.GroupKind(), ref.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't map %s to resource: %w", ref.GroupVersionKind(), err)
+	}
+
+	var resource dynamic.ResourceInterface = s.client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resource = s.client.Resource(mapping.Resource).Namespace(ref.Namespace)
+	}
+
+	obj, err := resource.Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (s *ClientSource) List(ctx context.Context, gk schema.GroupKind, namespace string) ([]*unstructured.Unstructured, error) {
+	mapping, err := s.mapper.RESTMapping(gk)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't map %s to resource: %w", gk, err)
+	}
+
+	var resource dynamic.ResourceInterface = s.client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resource = s.client.Resource(mapping.Resource).Namespace(namespace)
+	} else {
+		namespace = ""
+	}
+
+	key := gk.String() + "/" + namespace
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if objs, found := s.lists[key]; found {
+		return objs, nil
+	}
+
+	list, err := resource.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+			s.lists[key] = nil
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	objs := make([]*unstructured.Unstructured, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	s.lists[key] = objs
+
+	return objs, nil
+}
+
+// CacheSource serves objects from the context's shared informer caches
+// (see kubeclient.Context.Store), so repeated queries (of all users of the
+// context) don't list the same objects over and over again. Kinds that
+// aren't served by the cluster (or that the user has no access to) are
+// treated as empty.
+type CacheSource struct {
+	kctx   *kubeclient.Context
+	mapper meta.RESTMapper
+}
+
+var _ Source = (*CacheSource)(nil)
+
+func NewCacheSource(kctx *kubeclient.Context) (*CacheSource, error) {
+	discoveryClient, err := kctx.DiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CacheSource{
+		kctx:   kctx,
+		mapper: restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient),
+	}, nil
+}
+
+// Mapper exposes the source's REST mapper (e.g., to resolve resources to kinds).
+func (s *CacheSource) Mapper() meta.RESTMapper {
+	return s.mapper
+}
+
+func (s *CacheSource) Get(ctx context.Context, ref ObjectRef) (*unstructured.Unstructured, error) {
+	mapping, err := s.mapper.RESTMapping(ref.GroupKind(), ref.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't map %s to resource: %w", ref.GroupVersionKind(), err)
+	}
+
+	key := ref.Name
+	namespace := ""
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		key = ref.Namespace + "/" + ref.Name
+		namespace = ref.Namespace
+	}
+
+	store, err := s.kctx.Store(ctx, mapping.Resource, namespace)
+	if err != nil || store == nil {
+		return nil, err
+	}
+
+	obj, exists, err := store.GetByKey(key)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	un, _ := obj.(*unstructured.Unstructured)
+	return un, nil
+}
+
+func (s *CacheSource) List(ctx context.Context, gk schema.GroupKind, namespace string) ([]*unstructured.Unstructured, error) {
+	mapping, err := s.mapper.RESTMapping(gk)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't map %s to resource: %w", gk, err)
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		namespace = ""
+	}
+
+	store, err := s.kctx.Store(ctx, mapping.Resource, namespace)
+	if err != nil || store == nil {
+		return nil, err
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, obj := range store.List() {
+		if un, ok := obj.(*unstructured.Unstructured); ok {
+			objs = append(objs, un)
+		}
+	}
+	return objs, nil
+}
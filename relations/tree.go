@@ -0,0 +1,89 @@
+package relations
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Every object takes exactly one line.
+var treeEscaper = strings.NewReplacer("\n", `\n`)
+
+type treeBranch struct {
+	id    string
+	label string
+}
+
+// exportTree prints a spanning tree of the graph starting from the root
+// (and then from every node that isn't reachable from it). Each object
+// is printed once - at the shortest path from the root. Arrows show the
+// direction of the relation: "→ owns" - the parent owns the child,
+// "← owns" - the child owns the parent.
+func exportTree(w io.Writer, graph *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	adjacent := map[string][]treeBranch{}
+	for _, edge := range graph.Edges {
+		adjacent[edge.From] = append(adjacent[edge.From], treeBranch{id: edge.To, label: "→ " + string(edge.Type)})
+		adjacent[edge.To] = append(adjacent[edge.To], treeBranch{id: edge.From, label: "← " + string(edge.Type)})
+	}
+
+	starts := []string{}
+	if _, found := graph.Node(graph.Root); found {
+		starts = append(starts, graph.Root)
+	}
+	for _, node := range graph.Nodes {
+		starts = append(starts, node.ID)
+	}
+
+	visited := map[string]bool{}
+	children := map[string][]treeBranch{}
+
+	for _, start := range starts {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+
+		queue := []string{start}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+
+			for _, branch := range adjacent[id] {
+				if visited[branch.id] {
+					continue
+				}
+				visited[branch.id] = true
+				children[id] = append(children[id], branch)
+				queue = append(queue, branch.id)
+			}
+		}
+
+		node, _ := graph.Node(start)
+		fmt.Fprintln(bw, treeEscaper.Replace(node.ObjectRef.String()))
+		printTreeBranches(bw, graph, children, start, "")
+	}
+
+	return bw.Flush()
+}
+
+func printTreeBranches(
+	w io.Writer,
+	graph *Graph,
+	children map[string][]treeBranch,
+	id string,
+	indent string,
+) {
+	for i, branch := range children[id] {
+		connector, nested := "├── ", "│   "
+		if i == len(children[id])-1 {
+			connector, nested = "└── ", "    "
+		}
+
+		node, _ := graph.Node(branch.id)
+		fmt.Fprintf(w, "%s%s%s: %s\n", indent, connector, treeEscaper.Replace(branch.label), treeEscaper.Replace(node.ObjectRef.String()))
+		printTreeBranches(w, graph, children, branch.id, indent+nested)
+	}
+}
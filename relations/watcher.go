@@ -0,0 +1,208 @@
+package relations
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+// Bursts of informer events (e.g., a rollout) are coalesced
+// into a single graph recomputation.
+const watcherDebounce = 100 * time.Millisecond
+
+// Watcher keeps the graph of objects related to a target up to date.
+// The objects are served from the context's shared informer caches.
+type Watcher struct {
+	kctx   *kubeclient.Context
+	preset Preset
+	target ObjectRef
+	depth  int
+	logger *logrus.Entry
+}
+
+func NewWatcher(
+	kctx *kubeclient.Context,
+	preset Preset,
+	target ObjectRef,
+	depth int,
+	logger *logrus.Entry,
+) *Watcher {
+	return &Watcher{
+		kctx:   kctx,
+		preset: preset,
+		target: target,
+		depth:  depth,
+		logger: logger.WithField("target", target.String()),
+	}
+}
+
+// Run blocks until the ctx is canceled. The onChange callback receives
+// the current graph and its diff with the previously reported one
+// (the very first diff is computed against an empty graph).
+func (w *Watcher) Run(ctx context.Context, onChange func(graph *Graph, diff GraphDiff)) error {
+	discoveryClient, err := w.kctx.DiscoveryClient()
+	if err != nil {
+		return err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient)
+
+	targetMapping, err := mapper.RESTMapping(w.target.GroupKind(), w.target.Version)
+	if err != nil {
+		return err
+	}
+
+	// Relations of namespaced objects (except through hubs, which aren't
+	// traversed anyway) don't leave the namespace.
+	namespace := metav1.NamespaceAll
+	if targetMapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace = w.target.Namespace
+	}
+
+	dirty := make(chan struct{}, 1)
+	markDirty := func() {
+		select {
+		case dirty <- struct{}{}:
+		default:
+		}
+	}
+
+	src := &storeSource{stores: map[schema.GroupKind]cache.Store{}}
+
+	for _, gk := range append(w.preset.Kinds(), w.target.GroupKind()) {
+		if _, found := src.stores[gk]; found {
+			continue
+		}
+
+		mapping, err := mapper.RESTMapping(gk)
+		if err != nil {
+			if !meta.IsNoMatchError(err) {
+				w.logger.WithError(err).WithField("kind", gk.String()).Warn("Couldn't map kind to resource")
+			}
+			continue
+		}
+
+		ns := metav1.NamespaceAll
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			ns = namespace
+		}
+
+		store, unsubscribe, err := w.kctx.Subscribe(ctx, mapping.Resource, ns, markDirty)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil // The context has been canceled.
+			}
+			w.logger.WithError(err).WithField("kind", gk.String()).Warn("Couldn't list objects")
+			continue
+		}
+		defer unsubscribe()
+
+		if store != nil {
+			src.stores[gk] = store
+		}
+	}
+
+	engine := w.preset.Engine(src)
+	graph := NewGraph()
+	markDirty()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-dirty:
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watcherDebounce):
+		}
+
+		// Events that arrived while waiting are covered by this recomputation.
+		select {
+		case <-dirty:
+		default:
+		}
+
+		next, err := w.compute(ctx, engine, src)
+		if err != nil {
+			w.logger.WithError(err).Warn("Couldn't compute related objects")
+			continue
+		}
+
+		diff := Diff(graph, next)
+		graph = next
+
+		if !diff.IsEmpty() {
+			onChange(graph, diff)
+		}
+	}
+}
+
+func (w *Watcher) compute(ctx context.Context, engine *Engine, src Source) (*Graph, error) {
+	root, err := src.Get(ctx, w.target)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return NewGraph(), nil // The target is (yet) gone.
+	}
+
+	return engine.RelatedTo(ctx, root, w.depth)
+}
+
+// storeSource serves objects from informer stores.
+type storeSource struct {
+	stores map[schema.GroupKind]cache.Store
+}
+
+var _ Source = (*storeSource)(nil)
+
+func (s *storeSource) Get(_ context.Context, ref ObjectRef) (*unstructured.Unstructured, error) {
+	store, found := s.stores[ref.GroupKind()]
+	if !found {
+		return nil, nil
+	}
+
+	key := ref.Name
+	if ref.Namespace != "" {
+		key = ref.Namespace + "/" + ref.Name
+	}
+
+	obj, exists, err := store.GetByKey(key)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	un, _ := obj.(*unstructured.Unstructured)
+	return un, nil
+}
+
+func (s *storeSource) List(_ context.Context, gk schema.GroupKind, namespace string) ([]*unstructured.Unstructured, error) {
+	store, found := s.stores[gk]
+	if !found {
+		return nil, nil
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, obj := range store.List() {
+		un, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		// Cluster-scoped objects have no namespace.
+		if namespace == "" || un.GetNamespace() == "" || un.GetNamespace() == namespace {
+			objs = append(objs, un)
+		}
+	}
+	return objs, nil
+}
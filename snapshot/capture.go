@@ -0,0 +1,205 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+
+	"github.com/iximiuz/kexp/kubeclient"
+)
+
+// Secrets' values are replaced with empty strings (unless asked otherwise)
+// and the redacted objects are marked with this annotation.
+const AnnotationRedacted = "kexp.iximiuz.com/redacted"
+
+// Query selects objects of a resource (same as a watch).
+type Query struct {
+	Group         string `json:"group"`
+	Version       string `json:"version"`
+	Resource      string `json:"resource"`
+	Namespace     string `json:"namespace,omitempty"`
+	Name          string `json:"name,omitempty"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+	FieldSelector string `json:"fieldSelector,omitempty"`
+}
+
+// Selection is what to capture: either the objects of the queries
+// or, if there are no queries, the objects of all listable resources
+// in the namespace (all namespaces if empty) matching the label selector.
+type Selection struct {
+	Queries []Query `json:"queries,omitempty"`
+
+	Namespace     string `json:"namespace,omitempty"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	IncludeSecretData bool `json:"includeSecretData,omitempty"`
+}
+
+// Capture takes a snapshot of the selected objects. Resources the user
+// can't list are skipped.
+func Capture(ctx context.Context, kctx *kubeclient.Context, sel Selection, logger *logrus.Entry) (*Snapshot, error) {
+	discoveryClient, err := kctx.DiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kctx.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{
+		Manifest: Manifest{
+			Context:    kctx.Name(),
+			Cluster:    kctx.Cluster(),
+			ClusterUID: kctx.ClusterUID(),
+		},
+	}
+
+	groups, resourceLists, err := discoveryClient.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("couldn't discover API resources: %w", err)
+	}
+	for _, g := range groups {
+		snap.Manifest.Groups = append(snap.Manifest.Groups, *g)
+	}
+	snap.Manifest.Resources = resourceLists
+
+	queries := sel.Queries
+	if len(queries) == 0 {
+		queries, err = allResourcesQueries(discoveryClient, sel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, q := range queries {
+		if q.Group == "core" {
+			q.Group = ""
+		}
+
+		fieldSelector := q.FieldSelector
+		if q.Name != "" {
+			if fieldSelector != "" {
+				fieldSelector += ","
+			}
+			fieldSelector += "metadata.name=" + q.Name
+		}
+
+		gvr := schema.GroupVersionResource{Group: q.Group, Version: q.Version, Resource: q.Resource}
+
+		list, err := client.
+			Resource(gvr).
+			Namespace(q.Namespace).
+			List(ctx, metav1.ListOptions{
+				LabelSelector: q.LabelSelector,
+				FieldSelector: fieldSelector,
+			})
+		if err != nil {
+			if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+				logger.
+					WithError(err).
+					WithField("resource", gvr.String()).
+					Debug("Skipping resource")
+				continue
+			}
+			return nil, fmt.Errorf("couldn't list %s: %w", gvr.String(), err)
+		}
+
+		for i := range list.Items {
+			obj := &list.Items[i]
+
+			key := gvr.String() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			snap.Add(Sanitize(obj, sel.IncludeSecretData), q.Resource)
+		}
+	}
+
+	return snap, nil
+}
+
+func allResourcesQueries(client discovery.DiscoveryInterface, sel Selection) ([]Query, error) {
+	resourceLists, err := client.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("couldn't discover preferred API resources: %w", err)
+	}
+
+	var queries []Query
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") || !hasVerb(res, "list") {
+				continue
+			}
+			if sel.Namespace != "" && !res.Namespaced {
+				continue
+			}
+
+			queries = append(queries, Query{
+				Group:         gv.Group,
+				Version:       gv.Version,
+				Resource:      res.Name,
+				Namespace:     sel.Namespace,
+				LabelSelector: sel.LabelSelector,
+			})
+		}
+	}
+	return queries, nil
+}
+
+func hasVerb(res metav1.APIResource, verb string) bool {
+	for _, v := range res.Verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitize returns a copy of the object safe to write to disk: without
+// the managed fields and, for Secrets, without the values (unless
+// includeSecretData is set) and the last applied configuration.
+func Sanitize(obj *unstructured.Unstructured, includeSecretData bool) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(nil)
+
+	gvk := obj.GroupVersionKind()
+	if includeSecretData || gvk.Group != "" || gvk.Kind != "Secret" {
+		return obj
+	}
+
+	if data, found, _ := unstructured.NestedMap(obj.Object, "data"); found {
+		for key := range data {
+			data[key] = ""
+		}
+		_ = unstructured.SetNestedMap(obj.Object, data, "data")
+	}
+	unstructured.RemoveNestedField(obj.Object, "stringData")
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationRedacted] = "true"
+	// The last applied configuration would leak the values too.
+	delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	obj.SetAnnotations(annotations)
+
+	return obj
+}
@@ -0,0 +1,221 @@
+// Package snapshot dumps Kubernetes objects to a tarball of YAML files
+// with a manifest index and loads them back.
+//
+// Layout of a (gzipped) snapshot tarball:
+//
+//	manifest.yaml
+//	objects/<group>/<version>/<resource>/<namespace>/<name>.yaml
+//
+// The core API group is stored as "core" and cluster-scoped
+// objects under the "_cluster" namespace.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FormatVersion = 1
+
+	manifestPath = "manifest.yaml"
+	objectsDir   = "objects"
+
+	clusterScope = "_cluster"
+)
+
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+
+	// Where the objects come from.
+	Context    string `json:"context"`
+	Cluster    string `json:"cluster"`
+	ClusterUID string `json:"clusterUID"`
+
+	// Discovery data needed to serve the objects offline.
+	Groups    []metav1.APIGroup         `json:"groups,omitempty"`
+	Resources []*metav1.APIResourceList `json:"resources,omitempty"`
+
+	Objects []Entry `json:"objects"`
+}
+
+// Entry is an index record of a snapshotted object.
+type Entry struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+}
+
+type Snapshot struct {
+	Manifest Manifest
+
+	// In the manifest's index order.
+	Objects []*unstructured.Unstructured
+}
+
+// Add appends the object (served as the given resource) to the snapshot.
+func (s *Snapshot) Add(obj *unstructured.Unstructured, resource string) {
+	gvk := obj.GroupVersionKind()
+
+	s.Objects = append(s.Objects, obj)
+	s.Manifest.Objects = append(s.Manifest.Objects, Entry{
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Resource:  resource,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	})
+}
+
+// Write writes the snapshot as a gzipped tarball.
+func Write(w io.Writer, snap *Snapshot) error {
+	if len(snap.Objects) != len(snap.Manifest.Objects) {
+		return errors.New("snapshot index doesn't match its objects")
+	}
+
+	manifest := snap.Manifest
+	manifest.FormatVersion = FormatVersion
+	if manifest.CreatedAt.IsZero() {
+		manifest.CreatedAt = time.Now().UTC()
+	}
+
+	manifest.Objects = append([]Entry{}, manifest.Objects...)
+	for i, entry := range manifest.Objects {
+		manifest.Objects[i].Path = entryPath(entry)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("couldn't encode manifest: %w", err)
+	}
+	if err := writeFile(tw, manifestPath, data, manifest.CreatedAt); err != nil {
+		return err
+	}
+
+	for i, obj := range snap.Objects {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("couldn't encode object %s: %w", manifest.Objects[i].Path, err)
+		}
+		if err := writeFile(tw, manifest.Objects[i].Path, data, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read loads a snapshot written by Write.
+func Read(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot (gzip): %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a snapshot (tar): %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+
+	data, found := files[manifestPath]
+	if !found {
+		return nil, errors.New("not a snapshot: no manifest")
+	}
+
+	snap := &Snapshot{}
+	if err := yaml.Unmarshal(data, &snap.Manifest); err != nil {
+		return nil, fmt.Errorf("couldn't decode manifest: %w", err)
+	}
+	if snap.Manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version %d", snap.Manifest.FormatVersion)
+	}
+
+	for _, entry := range snap.Manifest.Objects {
+		data, found := files[path.Clean(entry.Path)]
+		if !found {
+			return nil, fmt.Errorf("object file %s is missing", entry.Path)
+		}
+
+		// Going through JSON keeps integers integers.
+		jsonData, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode object %s: %w", entry.Path, err)
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(jsonData); err != nil {
+			return nil, fmt.Errorf("couldn't decode object %s: %w", entry.Path, err)
+		}
+		snap.Objects = append(snap.Objects, obj)
+	}
+
+	return snap, nil
+}
+
+func entryPath(e Entry) string {
+	group := e.Group
+	if group == "" {
+		group = "core"
+	}
+
+	namespace := e.Namespace
+	if namespace == "" {
+		namespace = clusterScope
+	}
+
+	return path.Join(objectsDir, group, e.Version, e.Resource, namespace, e.Name+".yaml")
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	}); err != nil {
+		return err
+	}
+
+	_, err := tw.Write(data)
+	return err
+}
@@ -0,0 +1,153 @@
+package snapshot
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestWriteRead(t *testing.T) {
+	deploy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "web"},
+		"spec":       map[string]interface{}{"replicas": int64(3)},
+	}}
+	ns := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": "default"},
+	}}
+
+	snap := &Snapshot{Manifest: Manifest{
+		CreatedAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Context:    "prod",
+		Cluster:    "prod-cluster",
+		ClusterUID: "6f1d2a4e",
+		Groups:     []metav1.APIGroup{{Name: "apps"}},
+	}}
+	snap.Add(deploy, "deployments")
+	snap.Add(ns, "namespaces")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, snap); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	if got.Manifest.FormatVersion != FormatVersion ||
+		got.Manifest.Context != "prod" ||
+		got.Manifest.ClusterUID != "6f1d2a4e" ||
+		!got.Manifest.CreatedAt.Equal(snap.Manifest.CreatedAt) ||
+		len(got.Manifest.Groups) != 1 {
+		t.Errorf("manifest = %+v, want %+v", got.Manifest, snap.Manifest)
+	}
+
+	wantPaths := []string{
+		"objects/apps/v1/deployments/default/web.yaml",
+		"objects/core/v1/namespaces/_cluster/default.yaml",
+	}
+	for i, entry := range got.Manifest.Objects {
+		if entry.Path != wantPaths[i] {
+			t.Errorf("objects[%d].path = %q, want %q", i, entry.Path, wantPaths[i])
+		}
+	}
+
+	// Integers stay integers.
+	if !reflect.DeepEqual(got.Objects, []*unstructured.Unstructured{deploy, ns}) {
+		t.Errorf("objects = %+v, want %+v", got.Objects, snap.Objects)
+	}
+}
+
+func TestWriteMismatchedIndex(t *testing.T) {
+	snap := &Snapshot{Manifest: Manifest{Objects: []Entry{{Kind: "Pod", Name: "web-0"}}}}
+	if err := Write(&bytes.Buffer{}, snap); err == nil {
+		t.Error("Write() succeeded, want error")
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	var archive bytes.Buffer
+	if err := Write(&archive, &Snapshot{}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr string
+	}{
+		{"not gzip", []byte("apiVersion: v1"), "not a snapshot (gzip)"},
+		{"truncated", archive.Bytes()[:len(archive.Bytes())/2], "not a snapshot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(tt.input)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Read() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	secret := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name": "db",
+				"annotations": map[string]interface{}{
+					"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"password":"c2VjcmV0"}}`,
+					"owner": "team-a",
+				},
+				"managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}},
+			},
+			"data":       map[string]interface{}{"password": "c2VjcmV0"},
+			"stringData": map[string]interface{}{"user": "admin"},
+		}}
+	}
+
+	original := secret()
+	got := Sanitize(original, false)
+
+	if !reflect.DeepEqual(original, secret()) {
+		t.Error("Sanitize() modified the original object")
+	}
+	if data, _, _ := unstructured.NestedStringMap(got.Object, "data"); !reflect.DeepEqual(data, map[string]string{"password": ""}) {
+		t.Errorf("data = %v, want the keys without values", data)
+	}
+	if _, found := got.Object["stringData"]; found {
+		t.Error("stringData wasn't removed")
+	}
+	if got.GetManagedFields() != nil {
+		t.Error("managed fields weren't removed")
+	}
+	if want := map[string]string{"owner": "team-a", AnnotationRedacted: "true"}; !reflect.DeepEqual(got.GetAnnotations(), want) {
+		t.Errorf("annotations = %v, want %v", got.GetAnnotations(), want)
+	}
+
+	withData := Sanitize(secret(), true)
+	if data, _, _ := unstructured.NestedStringMap(withData.Object, "data"); data["password"] != "c2VjcmV0" {
+		t.Errorf("data = %v, want the values kept", data)
+	}
+	if withData.GetManagedFields() != nil {
+		t.Error("managed fields weren't removed")
+	}
+
+	// Only core Secrets are redacted.
+	lookalike := secret()
+	lookalike.SetAPIVersion("example.com/v1")
+	if data, _, _ := unstructured.NestedStringMap(Sanitize(lookalike, false).Object, "data"); data["password"] != "c2VjcmV0" {
+		t.Errorf("data = %v, want a non-core Secret kept as is", data)
+	}
+}
@@ -1,6 +1,10 @@
 import axios from "axios";
 import axiosRetry from "axios-retry";
 
+import { BASE_PATH } from "../common/basePath";
+
+import { CSRF_COOKIE_NAME, CSRF_HEADER_NAME } from "./csrf";
+
 // eslint-disable-next-line import/no-named-as-default-member
 axiosRetry(axios, { retries: 3, retryDelay: axiosRetry.exponentialDelay });
 
@@ -17,11 +21,22 @@ export default class HttpClient {
 
     console.assert(path[0] === "/", "API request path must start with /. Got", path);
 
-    return await axios({
-      method,
-      url: this.apiServer + path,
-      params,
-      data,
-    });
+    try {
+      return await axios({
+        method,
+        url: this.apiServer + path,
+        params,
+        data,
+        xsrfCookieName: CSRF_COOKIE_NAME,
+        xsrfHeaderName: CSRF_HEADER_NAME,
+      });
+    } catch (e) {
+      // The session has expired (or the daemon has been restarted).
+      if (axios.isAxiosError(e) && e.response?.status === 401) {
+        const next = window.location.pathname + window.location.search;
+        window.location.assign(BASE_PATH + "/login?next=" + encodeURIComponent(next));
+      }
+      throw e;
+    }
   }
 }
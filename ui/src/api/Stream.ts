@@ -2,18 +2,61 @@
 import { v4 as uuidv4 } from "uuid";
 
 import { splitGV } from "../common/kubeutil";
-import type { KubeContext, KubeResource, KubeSelector, RawKubeObject } from "../common/types";
+import type { KubeContext, KubeObjectDescriptor, KubeResource, KubeSelector, RawKubeObject } from "../common/types";
 
-interface Handler {
-  resolve: (response: { json?: string, yaml?: string, event: string }) => void;
-  reject: (error: Error) => void;
+import { CSRF_QUERY_PARAM, csrfToken } from "./csrf";
+
+interface WatchResponse {
+  json?: string;
+  yaml?: string;
+  event: string;
+}
+
+export interface AggregatedWatchResponse extends WatchResponse {
+  context: string;
+  clusterUID: string;
+  aliases: string[]; // Other contexts pointing to the same cluster.
+}
+
+interface Handler<T = WatchResponse> {
+  resolve(response: T): void;
+  reject(error: Error): void;
+}
+
+export interface RelatedGraphNode {
+  id: string;
+  group: string;
+  version: string;
+  kind: string;
+  namespace?: string;
+  name: string;
+  object?: RawKubeObject;
+}
+
+export interface RelatedGraphEdge {
+  from: string;
+  to: string;
+  type: string;
+}
+
+export interface RelatedGraphDiff {
+  root: string;
+  nodes: {
+    added: RelatedGraphNode[];
+    updated: RelatedGraphNode[];
+    removed: RelatedGraphNode[];
+  };
+  edges: {
+    added: RelatedGraphEdge[];
+    removed: RelatedGraphEdge[];
+  };
 }
 
 export default class Stream {
   constructor(
     private wsServer: string,
     private socket: WebSocket | null = null,
-    private handlers: Record<string, Handler>,
+    private handlers: Record<string, Handler<unknown>>,
   ) {
     this.wsServer = wsServer;// .replace(/\/*\s*$/, '');
     this.socket = null;
@@ -28,7 +71,9 @@ export default class Stream {
     return new Promise((resolve, reject) => {
       let connecting = true;
 
-      this.socket = new WebSocket(this.wsServer);
+      const url = new URL(this.wsServer);
+      url.searchParams.set(CSRF_QUERY_PARAM, csrfToken());
+      this.socket = new WebSocket(url.toString());
 
       this.socket.addEventListener("open", () => {
         connecting = false;
@@ -57,6 +102,15 @@ export default class Stream {
           return;
         }
 
+        if (reply.type === "goingAway") {
+          // The server is shutting down - the pending calls won't get replies.
+          console.warn("Stream is going away:", reply.reason);
+          const handlers = Object.values(this.handlers);
+          this.handlers = {};
+          handlers.forEach((handler) => handler.reject(new Error(reply.reason)));
+          return;
+        }
+
         const handler = this.handlers[reply.id];
         if (!handler) {
           console.error("Unregistered message ID", reply);
@@ -127,6 +181,110 @@ export default class Stream {
     return callId;
   }
 
+  watchRelatedKubeObjects(
+    kubeContext: KubeContext,
+    target: KubeObjectDescriptor,
+    preset: string,
+    callback: (error: Error | null, diff: RelatedGraphDiff | null) => void,
+  ) {
+    if (!this.socket) {
+      throw new Error("Stream has not been connected yet");
+    }
+
+    const [group, version] = splitGV(target.resource.groupVersion);
+    const callId = this._callId();
+    const handler: Handler<RelatedGraphDiff> = {
+      resolve: (response) => {
+        try {
+          callback(null, response);
+        } finally {
+          this.handlers[callId] = handler;
+        }
+      },
+      reject: (err) => {
+        callback(err, null);
+      },
+    };
+
+    this.handlers[callId] = handler;
+
+    this.socket.send(JSON.stringify({
+      type: "call",
+      id: callId,
+      method: "kubeObjects.watchRelated",
+      params: {
+        context: kubeContext.name,
+        group: group || "core",
+        version,
+        resource: target.resource.name,
+        namespace: target.namespace,
+        name: target.name,
+        preset,
+      },
+    }));
+
+    return callId;
+  }
+
+  // Watches the resource in several (or all, if no contexts are given) contexts at once.
+  // Errors of individual contexts don't stop the watch.
+  watchAggregatedKubeObjects(
+    kubeContexts: KubeContext[],
+    kubeResource: KubeResource,
+    selector: KubeSelector,
+    callback: (error: Error | null, object: RawKubeObject | null, response: AggregatedWatchResponse | null) => void,
+  ) {
+    if (!this.socket) {
+      throw new Error("Stream has not been connected yet");
+    }
+
+    const [group, version] = splitGV(kubeResource.groupVersion);
+    const callId = this._callId();
+    const handler: Handler<AggregatedWatchResponse> = {
+      resolve: (response) => {
+        if (!response.json) {
+          callback(new Error("No manifest (JSON) in response"), null, response);
+          return;
+        }
+
+        try {
+          callback(null, JSON.parse(response.json) as RawKubeObject, response);
+        } catch (e) {
+          callback(new Error(`Failed to parse Kubernetes manifest (JSON): ${e}`), null, response);
+        } finally {
+          this.handlers[callId] = handler;
+        }
+      },
+      reject: (err) => {
+        try {
+          callback(err, null, null);
+        } finally {
+          this.handlers[callId] = handler;
+        }
+      },
+    };
+
+    this.handlers[callId] = handler;
+
+    this.socket.send(JSON.stringify({
+      type: "call",
+      id: callId,
+      method: "kubeObjects.watchAggregated",
+      params: {
+        contexts: kubeContexts.map((ctx) => ctx.name),
+        group: group || "core",
+        version,
+        resource: kubeResource.name,
+        namespace: selector.namespace,
+        name: selector.name,
+        fieldSelector: selector.fields,
+        labelSelector: selector.labels,
+      },
+    }));
+
+    return callId;
+  }
+
   unwatchKubeObjects(watchId: string) {
     if (!this.socket) {
       throw new Error("Stream has not been connected yet");
@@ -0,0 +1,16 @@
+// The daemon hands out the CSRF token in a cookie (readable only by
+// the UI's own origin). Axios sends it back in the X-XSRF-TOKEN header
+// automatically; WebSocket upgrades have to pass it in the query string.
+export const CSRF_COOKIE_NAME = "XSRF-TOKEN";
+export const CSRF_HEADER_NAME = "X-XSRF-TOKEN";
+export const CSRF_QUERY_PARAM = "csrfToken";
+
+export function csrfToken(): string {
+  for (const cookie of document.cookie.split(";")) {
+    const [name, ...value] = cookie.trim().split("=");
+    if (name === CSRF_COOKIE_NAME) {
+      return decodeURIComponent(value.join("="));
+    }
+  }
+  return "";
+}
@@ -0,0 +1,11 @@
+import BaseResource from "./Base";
+
+export default class ConfigResource extends BaseResource {
+  constructor(httpClient) {
+    super(httpClient, "/config");
+  }
+
+  get() {
+    return this.request("GET", "");
+  }
+}
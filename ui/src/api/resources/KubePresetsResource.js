@@ -0,0 +1,11 @@
+import BaseResource from "./Base";
+
+export default class KubePresetsResource extends BaseResource {
+  constructor(httpClient) {
+    super(httpClient, "/kube/v1/contexts");
+  }
+
+  list(ctx) {
+    return this.request("GET", `/${ctx}/presets/`);
+  }
+}
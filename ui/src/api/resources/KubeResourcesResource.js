@@ -5,7 +5,16 @@ export default class KubeResourcesResource extends BaseResource {
     super(httpClient, "/kube/v1/contexts");
   }
 
-  list(ctx) {
-    return this.request("GET", `/${ctx}/resources/`);
+  async list(ctx) {
+    const { resources, failedGroups } = await this.request("GET", `/${ctx}/resources/`);
+    if (failedGroups.length > 0) {
+      console.warn("Some API groups could not be discovered", failedGroups);
+    }
+    return resources;
+  }
+
+  // Every served group/version (not only the preferred ones).
+  listAll(ctx) {
+    return this.request("GET", `/${ctx}/resources/`, { mode: "all" });
   }
 }
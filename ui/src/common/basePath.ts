@@ -0,0 +1,3 @@
+// The path prefix the daemon is served under (see --base-path),
+// injected into index.html by the daemon. Empty in the dev mode.
+export const BASE_PATH: string = (window as unknown as { KEXP_BASE_PATH?: string }).KEXP_BASE_PATH || "";
@@ -0,0 +1,77 @@
+// Mirrors the daemon's policy package - the UI uses it only
+// to hide the actions the daemon would refuse anyway.
+
+const ACTION_VERBS = ["create", "update", "patch", "delete", "exec"];
+
+const WILDCARD = "*";
+
+export interface PolicyRule {
+  effect: "allow" | "deny";
+  verbs?: string[];
+  contexts?: string[];
+  resources?: string[];
+  namespaces?: string[];
+}
+
+export interface Policy {
+  readOnly: boolean;
+  rules: PolicyRule[];
+}
+
+export interface PolicyAction {
+  verb: string;
+  context: string;
+  group: string;
+  resource: string;
+  namespace?: string;
+  name?: string;
+}
+
+export function policyAllows(policy: Policy | null, action: PolicyAction): boolean {
+  if (!policy || !ACTION_VERBS.includes(action.verb)) {
+    return true;
+  }
+
+  if (policy.readOnly) {
+    return false;
+  }
+
+  const rule = policy.rules.find((r) => ruleMatches(r, action));
+  return rule ? rule.effect === "allow" : true;
+}
+
+function ruleMatches(rule: PolicyRule, action: PolicyAction): boolean {
+  if (!matchesAny(rule.verbs, action.verb) || !matchesAny(rule.contexts, action.context)) {
+    return false;
+  }
+
+  const namespace = affectedNamespace(action);
+  if (rule.namespaces?.length && (!namespace || !matchesAny(rule.namespaces, namespace))) {
+    return false;
+  }
+
+  if (!rule.resources?.length) {
+    return true;
+  }
+
+  return rule.resources.some((res) => {
+    if (res === WILDCARD) {
+      return true;
+    }
+
+    const [name, ...group] = res.split(".");
+    return name === action.resource && (group.length === 0 || group.join(".") === action.group);
+  });
+}
+
+// The object's namespace or, for the Namespace objects, the namespace itself.
+function affectedNamespace(action: PolicyAction): string | undefined {
+  if (!action.group && action.resource === "namespaces" && !action.namespace) {
+    return action.name;
+  }
+  return action.namespace;
+}
+
+function matchesAny(patterns: string[] | undefined, value: string): boolean {
+  return !patterns?.length || patterns.includes(WILDCARD) || patterns.includes(value);
+}
@@ -1,9 +1,6 @@
 /* eslint-disable @typescript-eslint/ban-ts-comment */
-import { splitGVK } from "./kubeutil";
-
 import type {
   KubeObject,
-  KubeObjectMap,
   KubeResource,
   KubeResourceGroup,
   RawKubeObject,
@@ -333,82 +330,6 @@ export function isRelatedResource(target: KubeObject, res: KubeResource) {
   return !!(related[res.groupVersion] || {})[res.kind];
 }
 
-export function relatedObjects(
-  objects: Record<string, Record<string, KubeObjectMap>>,
-  target: KubeObject,
-): Record<string, Record<string, KubeObjectMap>> {
-  const related: Record<string, Record<string, KubeObjectMap>> = {};
-
-  const targetRelations = objectRelations(target);
-  while (Object.values(targetRelations).some((kinds) => Object.keys(kinds).length > 0)) {
-    for (const gv in targetRelations) {
-      if (!objects[gv]) {
-        delete targetRelations[gv];
-        continue;
-      }
-
-      for (const kind in targetRelations[gv]) {
-        if (!objects[gv][kind]) {
-          delete targetRelations[gv][kind];
-          continue;
-        }
-
-        // @ts-ignore-next-line
-        if (targetRelations[gv][kind].via) {
-          // @ts-ignore-next-line
-          const [viagv, viakind] = splitGVK(targetRelations[gv][kind].via);
-          if (!objects[viagv] || !objects[viagv][viakind]) {
-            delete targetRelations[gv][kind];
-            continue;
-          }
-
-          if (!related[viagv] || !related[viagv][viakind]) {
-            if (!targetRelations[viagv] || !targetRelations[viagv][viakind]) {
-              // No chance of finding any related objects anymore.
-              delete targetRelations[gv][kind];
-            } else {
-              // We'll have to revisit this one later.
-            }
-            continue;
-          }
-
-          for (const rel of Object.values(related[viagv][viakind])) {
-            const relations = objectRelations(rel); // relative's relations
-            if (!relations[gv] || !relations[gv][kind]) {
-              continue;
-            }
-
-            // Checking for transitive relations.
-            for (const obj of Object.values(objects[gv][kind])) {
-              // @ts-ignore-next-line
-              if (relations[gv][kind](rel, obj)) {
-                related[gv] = related[gv] || {};
-                related[gv][kind] = related[gv][kind] || {};
-                related[gv][kind][obj.ident] = obj;
-              }
-            }
-          }
-          delete targetRelations[gv][kind];
-        } else {
-          for (const obj of Object.values(objects[gv][kind])) {
-            if (typeof targetRelations[gv][kind] !== "function") {
-              console.warn(`Invalid relation for ${gv}/${kind}`, target, targetRelations);
-            // @ts-ignore
-            } else if (targetRelations[gv][kind](target, obj)) {
-              related[gv] = related[gv] || {};
-              related[gv][kind] = related[gv][kind] || {};
-              related[gv][kind][obj.ident] = obj;
-            }
-          }
-          delete targetRelations[gv][kind];
-        }
-      }
-    }
-  }
-
-  return related;
-}
-
 function _eventRelatesToObject(evt: KubeObject, obj: KubeObject): boolean {
   return isCoreV1Event(evt.raw) && obj.isEponymous(evt.raw.involvedObject) && evt.raw.involvedObject.uid === obj.raw.metadata.uid;
 }
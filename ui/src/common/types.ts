@@ -31,6 +31,15 @@ export interface KubeResourceGroup {
   resources?: KubeResource[];
 }
 
+// A graph preset served by the daemon (built-in or user-defined).
+export interface KubeGraphPreset {
+  name: string;
+  description?: string;
+  extends?: string;
+  kinds?: string[];
+  source: string;
+}
+
 export type KubeObjectIdent = string;
 
 export interface KubeObjectDescriptor {
@@ -1,7 +1,6 @@
 import { useKubeDataStore } from "../stores";
 
 import { useCleaner } from "./cleaner";
-import { isRelatedResource, isRelatedResourceGroup, relatedObjects } from "./relations";
 import type {
   KubeContext,
   KubeObjectDescriptor,
@@ -13,19 +12,19 @@ import type {
 
 // TODO: Garbage collect evicted objects.
 
+// The graph of related objects is computed (and kept up to date) server-side.
 export class RelatedWatcher {
   constructor(
     private ctx: KubeContext,
     private target: KubeObjectDescriptor,
-    private _targetObject: KubeObject | null = null,
-    private _allObjects: Record<string, Record<string, KubeObjectMap>> = {},
-    private _relatedObjects: Record<string, Record<string, KubeObjectMap>> = {},
+    private preset: string = "related",
+    private _objects: KubeObjectMap = {},
     private _listeners: (() => void)[] = [],
-    private _initialized: boolean = false,
     private _cleaner = useCleaner(),
   ) {
     this.ctx = ctx;
     this.target = target;
+    this.preset = preset;
   }
 
   destroy() {
@@ -33,116 +32,32 @@ export class RelatedWatcher {
   }
 
   objects(): KubeObject[] {
-    if (!this._targetObject) {
-      return [];
-    }
-
-    const objs: KubeObjectMap = {
-      [this.target.ident]: this._targetObject,
-    };
-
-    for (const groupVersion in this._relatedObjects) {
-      for (const kind in this._relatedObjects[groupVersion]) {
-        for (const ident in this._relatedObjects[groupVersion][kind]) {
-          const obj = this._relatedObjects[groupVersion][kind][ident];
-          if (!obj.evicted) {
-            objs[ident] = obj;
-          }
-        }
-      }
-    }
-
-    return Object.values(objs);
+    return Object.values(this._objects).filter((obj) => !obj.evicted);
   }
 
   async watch() {
-    const loadings = [];
-
-    const selector: KubeSelector = {
-      namespace: this.target.namespace,
-      name: this.target.name,
-    };
-
-    const [target, release] = await useKubeDataStore().fetchObjects(this.ctx, this.target.resource, selector);
-    if (Object.keys(target).length === 0) {
-      return;
-    }
-
-    this._targetObject = target[Object.keys(target)[0]];
-    if (!this._cleaner.addCleanup(release)) {
-      return;
-    }
-
-    const unwatch = await useKubeDataStore().watchObjects(this.ctx, this.target.resource, selector, (err, obj) => {
+    const unwatch = await useKubeDataStore().watchRelatedObjects(this.ctx, this.target, this.preset, (err, upserted, removed) => {
       if (err) {
-        console.error("RelatedWatcher: watch target error", this.target, err);
+        console.error("RelatedWatcher: watch related error", this.target, err);
         return;
       }
 
-      if (!obj || obj.evicted) {
-        this._targetObject = null;
-      } else {
-        this._targetObject = obj;
+      for (const obj of upserted) {
+        this._objects[obj.ident] = obj;
       }
-    });
-    if (!this._cleaner.addCleanup(unwatch)) {
-      return;
-    }
-
-    for (const rg of useKubeDataStore().resourceGroups(this.ctx)) {
-      if (isRelatedResourceGroup(this._targetObject, rg)) {
-        for (const res of (rg.resources || [])) {
-          if (isRelatedResource(this._targetObject, res)) {
-            loadings.push(this._loadObjects(res));
-          }
-        }
+      for (const obj of removed) {
+        delete this._objects[obj.ident];
       }
-    }
-
-    await Promise.all(loadings);
-    this._initialized = true;
 
-    this._computeRelatedObjects();
-  }
-
-  addEventListener(listener: () => void) {
-    this._listeners.push(listener);
-  }
-
-  async _loadObjects(res: KubeResource) {
-    const [batch, release] = await useKubeDataStore().fetchObjects(this.ctx, res);
-    if (!this._cleaner.addCleanup(release)) {
-      return;
-    }
-
-    _set(this._allObjects, [res.groupVersion, res.kind], batch);
-
-    const unwatch = await useKubeDataStore().watchObjects(this.ctx, res, {} as KubeSelector, (err, obj) => {
-      if (err || !obj) {
-        console.error("RelatedWatcher: watch resource error", res, err);
-        return;
-      }
-
-      _set(this._allObjects, [res.groupVersion, res.kind, obj.ident], obj);
-
-      if (this._initialized) {
-        // Keep the related object graph up to date.
-        this._computeRelatedObjects();
-
-        for (const fn of this._listeners) {
-          fn();
-        }
+      for (const fn of this._listeners) {
+        fn();
       }
     });
     this._cleaner.addCleanup(unwatch);
   }
 
-  _computeRelatedObjects() {
-    if (this._targetObject) {
-      this._relatedObjects = relatedObjects(this._allObjects || {}, this._targetObject);
-    } else {
-      this._relatedObjects = {};
-    }
+  addEventListener(listener: () => void) {
+    this._listeners.push(listener);
   }
 }
 
@@ -195,16 +110,3 @@ export class ResourceWatcher {
     this._listeners.push(listener);
   }
 }
-
-// eslint-disable-next-line @typescript-eslint/no-explicit-any
-function _set(obj: any, path: string[], value: unknown): void {
-  for (let i = 0; i < path.length - 1; i++) {
-    const key = path[i];
-    if (!obj[key]) {
-      obj[key] = {} as object;
-    }
-    obj = obj[key];
-  }
-
-  obj[path[path.length - 1]] = value;
-}
@@ -8,22 +8,27 @@ import App from "./App.vue";
 import BiXdm from "./api/BiXdm";
 import HttpClient from "./api/HttpClient";
 import Stream from "./api/Stream";
+import ConfigResource from "./api/resources/ConfigResource";
 import KubeContextsResource from "./api/resources/KubeContextsResource";
 import KubeObjectsResource from "./api/resources/KubeObjectsResource";
+import KubePresetsResource from "./api/resources/KubePresetsResource";
 import KubeResourcesResource from "./api/resources/KubeResourcesResource";
+import { BASE_PATH } from "./common/basePath";
 
 const host = `${window.location.host}`;
 const protocol = `${window.location.protocol}`;
 const wsProtocol = protocol === "https:" ? "wss:" : "ws:";
-const httpClient = new HttpClient(`${protocol}//${host}/api/`);
+const httpClient = new HttpClient(`${protocol}//${host}${BASE_PATH}/api/`);
 
 createApp(App)
   .use(createPinia()
     .use(() => ({
       biXdm: new BiXdm(),
+      resConfig: new ConfigResource(httpClient),
       resKubeContexts: new KubeContextsResource(httpClient),
       resKubeObjects: new KubeObjectsResource(httpClient),
+      resKubePresets: new KubePresetsResource(httpClient),
       resKubeResources: new KubeResourcesResource(httpClient),
-      streamProvider: () => new Stream(`${wsProtocol}//${host}/api/stream/v1/`),
+      streamProvider: () => new Stream(`${wsProtocol}//${host}${BASE_PATH}/api/stream/v1/`),
     })),
   ).mount("#app");
@@ -1,6 +1,8 @@
 /* eslint-disable @typescript-eslint/ban-ts-comment */
 import { defineStore, acceptHMRUpdate } from "pinia";
 
+import { policyAllows } from "../common/policy";
+import type { Policy, PolicyAction } from "../common/policy";
 import type { KubeObject } from "../common/types";
 
 import { useAllExplorerStore } from "./kubeExplorer/allExplorerStore";
@@ -10,6 +12,10 @@ export const useAppStore = defineStore({
   id: "appStore",
 
   state: () => ({
+    version: "",
+
+    policy: null as Policy | null,
+
     inspectedKubeObject: null as KubeObject | null,
 
     requestBuilder: null as {
@@ -18,6 +24,7 @@ export const useAppStore = defineStore({
   }),
 
   getters: {
+    allows: (state) => (action: PolicyAction) => policyAllows(state.policy, action),
   },
 
   actions: {
@@ -41,6 +48,13 @@ export const useAppStore = defineStore({
       });
     },
 
+    async fetchConfig() {
+      // @ts-ignore-next-line
+      const config = await this.resConfig.get();
+      this.version = config.version;
+      this.policy = config.policy;
+    },
+
     setInspectedKubeObject(ko: KubeObject | null) {
       this.inspectedKubeObject = ko;
     },
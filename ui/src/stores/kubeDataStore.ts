@@ -4,10 +4,12 @@ import { v4 as uuidv4 } from "uuid";
 import { reactive } from "vue";
 
 import type Stream from "../api/Stream";
+import type { RelatedGraphDiff, RelatedGraphNode } from "../api/Stream";
 import { splitGV } from "../common/kubeutil";
 import type {
   ClusterUID,
   KubeContext,
+  KubeGraphPreset,
   KubeObject,
   KubeObjectDescriptor,
   KubeObjectIdent,
@@ -30,6 +32,8 @@ export const useKubeDataStore = defineStore({
 
     _resourceGroupsByContext: {} as Record<string, KubeResourceGroup[]>,
 
+    _presetsByContext: {} as Record<string, KubeGraphPreset[]>,
+
     _objectsByResource: {} as { [key: string]: KubeObjectMap },
 
     _streamPromise: undefined as Promise<Stream> | undefined,
@@ -65,6 +69,12 @@ export const useKubeDataStore = defineStore({
       };
     },
 
+    presets: (state) => {
+      return (ctx: KubeContext): KubeGraphPreset[] => {
+        return state._presetsByContext[ctx.name] || [];
+      };
+    },
+
     resource(): (ctx: KubeContext, groupVersion: string, kind: string) => KubeResource | null {
       return (ctx, groupVersion, kind) => {
         const groups = this.resourceGroups(ctx);
@@ -124,6 +134,12 @@ export const useKubeDataStore = defineStore({
       return this.resourceGroups(ctx);
     },
 
+    async fetchPresets(ctx: KubeContext) {
+      // @ts-ignore
+      this._presetsByContext[ctx.name] = await this.resKubePresets.list(ctx.name);
+      return this._presetsByContext[ctx.name];
+    },
+
     async fetchObjects(ctx: KubeContext, resource: KubeResource, selector?: KubeSelector): Promise<[KubeObjectMap, () => void]> {
       this._ensureRefreshLoop();
 
@@ -182,17 +198,7 @@ export const useKubeDataStore = defineStore({
     ) {
       this._ensureRefreshLoop();
 
-      if (!this._streamPromise) {
-        this._streamPromise = (async() => {
-          // @ts-ignore-next-line
-          const stream = this.streamProvider();
-          await stream.connect();
-          console.debug("kubeDataStore: stream connected");
-          return stream;
-        })();
-      }
-
-      const stream = await this._streamPromise;
+      const stream = await this._ensureStream();
       const watchId = stream.watchKubeObjects(ctx, resource, selector || {}, (err: Error | null, rawObj: RawKubeObject | null, event?: { deleted?: boolean }) => {
         if (err || !rawObj) {
           console.error("kubeDataStore: stream watch error", err);
@@ -239,6 +245,102 @@ export const useKubeDataStore = defineStore({
       };
     },
 
+    // Watches the (server-side computed) graph of objects related to the target.
+    async watchRelatedObjects(
+      ctx: KubeContext,
+      target: KubeObjectDescriptor,
+      preset: string,
+      callback: (err: Error | null, upserted: KubeObject[], removed: KubeObject[]) => void,
+    ) {
+      this._ensureRefreshLoop();
+
+      const stream = await this._ensureStream();
+      const used: Record<KubeObjectIdent, KubeObject> = {};
+
+      const toKubeObject = (node: RelatedGraphNode, now: number): KubeObject | null => {
+        const groupVersion = node.group ? `${node.group}/${node.version}` : node.version;
+        const resource = this.resource(ctx, groupVersion, node.kind);
+        if (!resource) {
+          return null;
+        }
+
+        const objects = this.objects(ctx, resource);
+        const ident = _objectIdent(ctx.clusterUID, resource, { metadata: { name: node.name, namespace: node.namespace } });
+        if (node.object) {
+          if (objects[ident]) {
+            objects[ident]._patch(node.object, now);
+          } else {
+            objects[ident] = _bakedObject(ctx.clusterUID, resource, node.object);
+            objects[ident]._refresh(now);
+          }
+        }
+        return objects[ident] || null;
+      };
+
+      const watchId = stream.watchRelatedKubeObjects(ctx, target, preset, (err: Error | null, diff: RelatedGraphDiff | null) => {
+        if (err || !diff) {
+          console.error("kubeDataStore: stream watch related error", err);
+          callback(err, [], []);
+          return;
+        }
+
+        const now = Date.now();
+        const upserted: KubeObject[] = [];
+        for (const node of [...diff.nodes.added, ...diff.nodes.updated]) {
+          const obj = toKubeObject(node, now);
+          if (obj) {
+            obj._usedBy[watchId] = true;
+            used[obj.ident] = obj;
+            upserted.push(obj);
+          }
+        }
+
+        const removed: KubeObject[] = [];
+        for (const node of diff.nodes.removed) {
+          const obj = toKubeObject(node, now);
+          if (obj) {
+            delete obj._usedBy[watchId];
+            delete used[obj.ident];
+            removed.push(obj);
+            if (Object.keys(obj._usedBy).length === 0) {
+              this._evictObject(obj);
+            }
+          }
+        }
+
+        try {
+          callback(null, upserted, removed);
+        } catch (e) {
+          console.warn("kubeDataStore: watch related callback failed", e);
+        }
+      });
+
+      return () => {
+        stream.unwatchKubeObjects(watchId);
+
+        Object.values(used).forEach((obj) => {
+          delete obj._usedBy[watchId];
+          if (Object.keys(obj._usedBy).length === 0) {
+            this._evictObject(obj);
+          }
+        });
+      };
+    },
+
+    async _ensureStream(): Promise<Stream> {
+      if (!this._streamPromise) {
+        this._streamPromise = (async() => {
+          // @ts-ignore-next-line
+          const stream = this.streamProvider();
+          await stream.connect();
+          console.debug("kubeDataStore: stream connected");
+          return stream;
+        })();
+      }
+
+      return this._streamPromise;
+    },
+
     async updateObject(ctx: KubeContext, obj: KubeObject, manifest: object) {
       this._ensureRefreshLoop();
 
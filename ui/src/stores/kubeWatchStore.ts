@@ -251,8 +251,9 @@ export const useKubeWatchStore = defineStore({
       preset?: string,
     ) {
       const watch = _relatedWatch(ctx, target, preset);
+      const watcherPreset = _isSharedRelatedPreset(preset) ? undefined : preset;
 
-      await this._addWatch(ctx, watch, () => new RelatedWatcher(ctx, target));
+      await this._addWatch(ctx, watch, () => new RelatedWatcher(ctx, target, watcherPreset));
     },
 
     getRelatedWatch(
@@ -392,11 +393,19 @@ function _relatedWatchId(ctx: KubeContext, target: KubeObjectDescriptor, preset?
   return `${ctx.name}/${target.ident}/related${preset ? `/${preset}` : ""}`;
 }
 
+// The built-in presets share the graph of all related objects (see _watchMatch).
+// User-defined presets are computed server-side, each in its own graph.
+function _isSharedRelatedPreset(preset?: string): boolean {
+  return !preset || preset === WATCH_PRESET_APPLICATION;
+}
+
 function _relatedWatch(ctx: KubeContext, target: KubeObjectDescriptor, preset?: string): RelatedWatch {
   return {
     kind: WATCH_KIND_RELATED,
     id: _relatedWatchId(ctx, target, preset),
-    bindingId: `${ctx.name}/${target.ident}/related`, // one-to-many relation
+    bindingId: _isSharedRelatedPreset(preset)
+      ? `${ctx.name}/${target.ident}/related` // one-to-many relation
+      : `${ctx.name}/${target.ident}/related/${preset}`,
     createdAt: Date.now(),
     context: ctx.name,
     target,